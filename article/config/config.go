@@ -2,19 +2,74 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"time"
 )
 
 // Config contains the configuration of the server and database
 type Config struct {
-	Server Server `json:"server"`
-	DB     DB     `json:"db"`
+	Server    Server    `json:"server"`
+	DB        DB        `json:"db"`
+	Auth      Auth      `json:"auth"`
+	RateLimit RateLimit `json:"rate_limit"`
+	CORS      CORS      `json:"cors"`
 }
 
-// Server configuration
+// Server configuration, the unset timeouts take the defaults of WithDefaults
 type Server struct {
 	Host string `json:"host"`
 	Port string `json:"port"`
+	// ReadTimeout limits reading a whole request, ReadHeaderTimeout its headers
+	ReadTimeout       Duration `json:"read_timeout"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout"`
+	// WriteTimeout limits the time from the end of the request headers to the end of every response.
+	// It cuts off an export streaming for longer, so it is not set by default
+	WriteTimeout Duration `json:"write_timeout"`
+	// HandlerTimeout limits the requests that are not streamed, past it they stop, roll back their change and answer 503
+	HandlerTimeout Duration `json:"handler_timeout"`
+	// IdleTimeout is how long a keep-alive connection waits for the next request
+	IdleTimeout Duration `json:"idle_timeout"`
+	// ShutdownGrace is how long the requests in flight get to finish when the server stops
+	ShutdownGrace Duration `json:"shutdown_grace"`
+	// ShutdownDelay is how long /readyz reports draining before the server stops accepting connections,
+	// time for the load balancers to stop sending requests. It is not delayed when unset
+	ShutdownDelay Duration `json:"shutdown_delay"`
+}
+
+// WithDefaults returns s with the unset timeouts set to their defaults, WriteTimeout has none
+func (s Server) WithDefaults() Server {
+	for _, d := range []struct {
+		value *Duration
+		def   time.Duration
+	}{
+		{&s.ReadTimeout, 30 * time.Second},
+		{&s.ReadHeaderTimeout, 5 * time.Second},
+		{&s.HandlerTimeout, time.Minute},
+		{&s.IdleTimeout, 2 * time.Minute},
+		{&s.ShutdownGrace, 30 * time.Second},
+	} {
+		if *d.value <= 0 {
+			*d.value = Duration(d.def)
+		}
+	}
+	return s
+}
+
+// Duration is a time.Duration written as a string like "15s" or "1m30s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"15s\": %v", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
 }
 
 // DB configuration
@@ -27,6 +82,56 @@ type DB struct {
 	Name     string `json:"name"`
 }
 
+// Auth configuration of the routes that change data, requests need one of the api keys or a signed token
+type Auth struct {
+	APIKeys []APIKey `json:"api_keys"`
+	// JWTSecret verifies the HS256 signature of bearer tokens, tokens are refused when it is empty
+	JWTSecret string `json:"jwt_secret"`
+	// JWTIssuer and JWTAudience are checked against the iss and aud claims when set
+	JWTIssuer   string `json:"jwt_issuer"`
+	JWTAudience string `json:"jwt_audience"`
+}
+
+// APIKey is a static key sent in the X-API-Key header and the subject it authenticates.
+// Role is admin or publisher, a publisher role is limited to the articles of Publisher
+type APIKey struct {
+	Key       string `json:"key"`
+	Subject   string `json:"subject"`
+	Role      string `json:"role"`
+	Publisher string `json:"publisher"`
+}
+
+// RateLimit configuration of the route groups, each client has its own bucket per group
+type RateLimit struct {
+	Read  Limit `json:"read"`
+	Write Limit `json:"write"`
+	// TrustedProxies are the IPs or CIDRs of the load balancers and reverse proxies in front of the server,
+	// the client IP of their requests is read from X-Forwarded-For
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+// Limit is a token bucket refilled with Rate requests per second up to Burst, a zero Rate disables it
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// CORS configuration of the browsers allowed to call the API from other origins, CORS is off when AllowedOrigins is empty
+type CORS struct {
+	// AllowedOrigins are origins like https://example.com, * allows any origin
+	AllowedOrigins []string `json:"allowed_origins"`
+	// AllowedMethods default to every method of the API
+	AllowedMethods []string `json:"allowed_methods"`
+	// AllowedHeaders are the request headers a browser may send, * allows any. They default to the headers the API reads
+	AllowedHeaders []string `json:"allowed_headers"`
+	// ExposedHeaders are the response headers a browser may read, they default to the headers the API sets
+	ExposedHeaders []string `json:"exposed_headers"`
+	// AllowCredentials lets browsers send cookies and authorization headers
+	AllowCredentials bool `json:"allow_credentials"`
+	// MaxAge is how many seconds a browser may cache a preflight response, zero leaves it to the browser
+	MaxAge int `json:"max_age"`
+}
+
 //  FromFile return a configuration from a given file
 func FromFile(path string) (*Config, error) {
 	bytes, err := ioutil.ReadFile(path)
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestFromFile(t *testing.T) {
@@ -12,8 +14,9 @@ func TestFromFile(t *testing.T) {
 		want    *Config
 		wantErr bool
 	}{
-		{"case 01", "./config.json", &Config{Server{"127.0.0.1", "8080"},
-			DB{"postgres", "127.0.0.1", "5432", "postgres", "", "articledb"}}, false},
+		{"case 01", "./config.json", &Config{Server{"127.0.0.1", "8080", Duration(30 * time.Second), Duration(5 * time.Second),
+			0, Duration(time.Minute), Duration(2 * time.Minute), Duration(30 * time.Second), Duration(5 * time.Second)},
+			DB{"postgres", "127.0.0.1", "5432", "postgres", "", "articledb"}, Auth{}, RateLimit{}, CORS{}}, false},
 		{"case 02", "./config.yml", &Config{}, true},
 		{"case 03", "./config_.json", &Config{}, true},
 		{"case 03", "./confi.json", &Config{}, true},
@@ -30,7 +33,57 @@ func TestFromFile(t *testing.T) {
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("FromFile() = %s, want %s", got, tt.want)
+				t.Errorf("FromFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerWithDefaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		server Server
+		want   Server
+	}{
+		{"case 01", Server{}, Server{ReadTimeout: Duration(30 * time.Second), ReadHeaderTimeout: Duration(5 * time.Second),
+			HandlerTimeout: Duration(time.Minute), IdleTimeout: Duration(2 * time.Minute), ShutdownGrace: Duration(30 * time.Second)}},
+		{"case 02", Server{Port: "8080", WriteTimeout: Duration(10 * time.Minute), ShutdownGrace: Duration(time.Minute)},
+			Server{Port: "8080", ReadTimeout: Duration(30 * time.Second), ReadHeaderTimeout: Duration(5 * time.Second),
+				WriteTimeout: Duration(10 * time.Minute), HandlerTimeout: Duration(time.Minute), IdleTimeout: Duration(2 * time.Minute),
+				ShutdownGrace: Duration(time.Minute)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.server.WithDefaults(); got != tt.want {
+				t.Errorf("WithDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		want    Duration
+		wantErr bool
+	}{
+		{"case 01", `"1m30s"`, Duration(90 * time.Second), false},
+		{"case 02", `"250ms"`, Duration(250 * time.Millisecond), false},
+		{"case 03", `30`, 0, true},
+		{"case 04", `"thirty seconds"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Duration
+			err := json.Unmarshal([]byte(tt.args), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("UnmarshalJSON() = %v, want %v", time.Duration(got), time.Duration(tt.want))
 			}
 		})
 	}
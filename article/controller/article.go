@@ -1,74 +1,202 @@
 package controller
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/femonofsky/articleMaker/article/model"
 	"github.com/gorilla/mux"
 	"io"
+	"io/ioutil"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
 	"time"
 )
 
+const (
+	// mergePatchType is the media type of a JSON Merge Patch
+	mergePatchType = "application/merge-patch+json"
+	// exportFlushRows is the number of exported articles sent to the client at once
+	exportFlushRows = 100
+)
+
 // ArticleController Handler
 type ArticleController struct {
 	logger *log.Logger
+	store  model.Store
 }
 
-// GetAll Handler: handle get all articles and can be filter by category,publisher, created_at, published_at
+// GetAll Handler: handle get all articles and can be filter by category, publisher
+// and ranges of created and published dates, q searches the title and body ranking the best matches first
+// The listing is paged with limit and either offset or cursor, and ordered with sort
 func (ac *ArticleController) GetAll(w io.Writer, r *http.Request) (interface{}, int, error) {
-	article := model.Article{}
-	if category := r.FormValue("category"); category != "" {
-		article.CategoryName = category
-	}
-	if publisher := r.FormValue("publisher"); publisher != "" {
-		article.PublisherName = publisher
+	filter, err := articleFilter(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
 	}
 
-	if createdAt := r.FormValue("created_at"); createdAt != "" {
-		vale, err := time.Parse(model.DateTimeLayout, createdAt)
-		if err != nil {
-			return nil, http.StatusBadRequest, fmt.Errorf("wrong format of date %v", err)
-		}
-		article.CreatedAt = vale
+	p, err := pageFromRequest(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
 	}
-	if publishedAt := r.FormValue("published_at"); publishedAt != "" {
-		vale, err := time.Parse(model.DateTimeLayout, publishedAt)
-		if err != nil {
-			return nil, http.StatusBadRequest, fmt.Errorf("wrong format of date %v", err)
-		}
-		article.PublishedAt = vale
+
+	articles, total, err := ac.store.WithContext(r.Context()).GetArticlesPage(filter, p)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
 	}
+	return newPage(articles, p, total), http.StatusOK, nil
+}
 
-	articles, err := model.GetArticles(article)
+// Export Handler: stream every article matching the filters of GetAll as NDJSON, one article per line.
+// Articles are written as they are read from the database and flushed every exportFlushRows lines
+func (ac *ArticleController) Export(w io.Writer, r *http.Request) (func(io.Writer) error, int, error) {
+	filter, err := articleFilter(r)
 	if err != nil {
 		return nil, http.StatusBadRequest, err
 	}
-	return articles, http.StatusOK, nil
+
+	write := func(out io.Writer) error {
+		enc := json.NewEncoder(out)
+		flusher, _ := out.(http.Flusher)
+		rows := 0
+		return ac.store.WithContext(r.Context()).EachArticle(filter, func(article *model.Article) error {
+			if err := enc.Encode(article); err != nil {
+				return err
+			}
+			if rows++; flusher != nil && rows%exportFlushRows == 0 {
+				flusher.Flush()
+			}
+			return nil
+		})
+	}
+	return write, http.StatusOK, nil
+}
+
+// articleFilter reads the article filters from the query string
+func articleFilter(r *http.Request) (model.ArticleFilter, error) {
+	filter := model.ArticleFilter{
+		Search:        r.FormValue("q"),
+		CategoryName:  r.FormValue("category"),
+		PublisherName: r.FormValue("publisher"),
+	}
+
+	var err error
+	if filter.CreatedAt, err = timeRange(r, "created"); err != nil {
+		return filter, err
+	}
+	if filter.PublishedAt, err = timeRange(r, "published"); err != nil {
+		return filter, err
+	}
+	return filter, nil
 }
 
-// Create Handler: Create a new Article
+// timeRange reads the bounds of a date filter named by prefix:
+// <prefix>_at matches exactly, <prefix>_from and <prefix>_to are inclusive,
+// <prefix>_after and <prefix>_before are exclusive
+func timeRange(r *http.Request, prefix string) (model.TimeRange, error) {
+	tr := model.TimeRange{}
+	bounds := []struct {
+		name  string
+		value []*time.Time
+	}{
+		{prefix + "_at", []*time.Time{&tr.From, &tr.To}},
+		{prefix + "_from", []*time.Time{&tr.From}},
+		{prefix + "_to", []*time.Time{&tr.To}},
+		{prefix + "_after", []*time.Time{&tr.After}},
+		{prefix + "_before", []*time.Time{&tr.Before}},
+	}
+	for _, bound := range bounds {
+		param := r.FormValue(bound.name)
+		if param == "" {
+			continue
+		}
+		vale, err := time.Parse(model.DateTimeLayout, param)
+		if err != nil {
+			return tr, fmt.Errorf("wrong format of date %v", err)
+		}
+		for _, value := range bound.value {
+			*value = vale
+		}
+	}
+	return tr, nil
+}
+
+// Create Handler: Create a new Article, a publisher can only create its own articles
+// and gets its name as publisher when the article has none
 func (ac *ArticleController) Create(w io.Writer, r *http.Request) (interface{}, int, error) {
 
 	article, err := model.Serialize(r.Body)
 	if err != nil {
 		return nil, http.StatusBadRequest, err
 	}
+	ownPublisher(r, &article.PublisherName)
+	if err = authorizeArticles(r, article.PublisherName); err != nil {
+		return nil, http.StatusForbidden, err
+	}
 	if err = article.Validate(); err != nil {
 		return nil, http.StatusBadRequest, err
 	}
 
-	err = model.CreateArticle(article)
+	err = ac.store.WithContext(r.Context()).CreateArticle(article)
 
 	if err != nil {
 		return nil, http.StatusBadRequest, err
 	}
+	setETag(w, article)
 
 	return article, http.StatusCreated, nil
 }
 
-// Get Handler: Get article using ID
+// Bulk Handler: import a json array or NDJSON stream of articles and report the outcome of each.
+// mode=atomic, the default, stores nothing when one article fails, mode=best_effort stores the valid ones.
+// Articles of a publisher the caller may not write for fail as forbidden
+func (ac *ArticleController) Bulk(w io.Writer, r *http.Request) (interface{}, int, error) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = atomicMode
+	}
+	if mode != atomicMode && mode != bestEffortMode {
+		return nil, http.StatusBadRequest, fmt.Errorf("mode must be %v or %v got: %v", atomicMode, bestEffortMode, mode)
+	}
+
+	articles, rejected, status, err := decodeBulk(w, r)
+	if err != nil {
+		return nil, status, err
+	}
+	for i, article := range articles {
+		if article == nil {
+			continue
+		}
+		ownPublisher(r, &article.PublisherName)
+		if err := authorizeArticles(r, article.PublisherName); err != nil {
+			// ImportArticles counts nil articles as failed, the error is put back below
+			articles[i], rejected[i] = nil, err
+		}
+	}
+	errs := ac.store.WithContext(r.Context()).ImportArticles(articles, mode == atomicMode)
+	for i, err := range rejected {
+		if err != nil {
+			errs[i] = err
+		} else if mode == atomicMode && errors.Is(errs[i], model.ErrCanceled) {
+			// nothing was stored, the whole import failed
+			return nil, http.StatusServiceUnavailable, errs[i]
+		}
+	}
+
+	result := newBulkResult(mode, articles, errs)
+	loggerFor(r, ac.logger).Printf("bulk import in %s mode: %d created, %d failed", mode, result.Created, result.Failed)
+	switch {
+	case result.Failed == 0:
+		return result, http.StatusCreated, nil
+	case mode == atomicMode:
+		return nil, http.StatusUnprocessableEntity, &bulkError{result: result}
+	}
+	return result, http.StatusMultiStatus, nil
+}
+
+// Get Handler: Get article using ID, answers 304 when If-None-Match lists the current ETag
 func (ac *ArticleController) Get(w io.Writer, r *http.Request) (interface{}, int, error) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -76,49 +204,132 @@ func (ac *ArticleController) Get(w io.Writer, r *http.Request) (interface{}, int
 		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v", id)
 	}
 
-	article, err := model.GetArticle(model.Article{ID: uint(id)})
+	article, err := ac.store.WithContext(r.Context()).GetArticle(id)
 	if err != nil {
 		return nil, http.StatusBadRequest, err
 	}
+	setETag(w, article)
+	if ifNoneMatch(r, article) {
+		return nil, http.StatusNotModified, nil
+	}
 	return article, http.StatusOK, nil
 
 }
 
-// Delete Handler: delete article by ID
+// Delete Handler: delete article by ID, honours If-Match. A publisher can only delete its own articles
 func (ac *ArticleController) Delete(w io.Writer, r *http.Request) (interface{}, int, error) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
 		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v, %v", id, err)
 	}
-	if err = model.DeleteArticle(id); err != nil {
+	current, err := ac.store.WithContext(r.Context()).GetArticle(id)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err = authorizeArticles(r, current.PublisherName); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	if !ifMatch(r, current) {
+		return nil, http.StatusPreconditionFailed, model.ErrArticleChanged
+	}
+	if err = ac.store.WithContext(r.Context()).DeleteArticle(id, current.Version); err != nil {
 		return nil, http.StatusBadRequest, err
 	}
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		loggerFor(r, ac.logger).Printf("article %d of %s deleted by %s", id, current.PublisherName, principal.Subject)
+	}
 
 	return nil, http.StatusNoContent, nil
 }
 
-// PUT Handler: Update article
+// PUT Handler: replace an article, every field is required and the stored article is returned.
+// Honours If-Match. A publisher can only replace its own articles and cannot hand them to another publisher
 func (ac *ArticleController) Put(w io.Writer, r *http.Request) (interface{}, int, error) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
 		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v", id)
 	}
+	current, err := ac.store.WithContext(r.Context()).GetArticle(id)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	if err = authorizeArticles(r, current.PublisherName); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	if !ifMatch(r, current) {
+		return nil, http.StatusPreconditionFailed, model.ErrArticleChanged
+	}
 	article, err := model.Serialize(r.Body)
 	if err != nil {
 		return nil, http.StatusBadRequest, err
 	}
+	ownPublisher(r, &article.PublisherName)
+	if err = authorizeArticles(r, article.PublisherName); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	if err = article.Validate(); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if err = ac.store.WithContext(r.Context()).UpdateArticle(id, article, current.Version); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	setETag(w, article)
+	return article, http.StatusOK, nil
+
+}
 
-	err = model.UpdateArticle(id, article)
+// Patch Handler: apply a JSON Merge Patch (RFC 7396) to an article, a null value clears a field.
+// Honours If-Match and the publisher scope of Put
+func (ac *ArticleController) Patch(w io.Writer, r *http.Request) (interface{}, int, error) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v", id)
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != mergePatchType && mediaType != "application/json") {
+			return nil, http.StatusUnsupportedMediaType, fmt.Errorf("content type must be %v got: %v", mergePatchType, contentType)
+		}
+	}
+
+	current, err := ac.store.WithContext(r.Context()).GetArticle(id)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	if err = authorizeArticles(r, current.PublisherName); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	if !ifMatch(r, current) {
+		return nil, http.StatusPreconditionFailed, model.ErrArticleChanged
+	}
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("unable to read request body: %v", err)
+	}
+	article, err := current.MergePatch(patch)
 	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("unable to upload article got: %v", err)
+		return nil, http.StatusBadRequest, err
+	}
+	ownPublisher(r, &article.PublisherName)
+	if err = authorizeArticles(r, article.PublisherName); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	if err = article.Validate(); err != nil {
+		return nil, http.StatusBadRequest, err
 	}
-	return &article, http.StatusOK, nil
 
+	if err = ac.store.WithContext(r.Context()).UpdateArticle(id, article, current.Version); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	setETag(w, article)
+	return article, http.StatusOK, nil
 }
 
-// newArticle creates a new Article Handle
-func newArticle(logger *log.Logger) *ArticleController {
-	return &ArticleController{logger: logger}
+// newArticle creates a new Article Handle of the articles of store
+func newArticle(logger *log.Logger, store model.Store) *ArticleController {
+	return &ArticleController{logger: logger, store: store}
 }
@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/config"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// clockSkew is the leeway given to the exp and nbf claims of a token
+const clockSkew = time.Minute
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string
+	// Method is how the caller authenticated, api_key or jwt
+	Method string
+	Role   Role
+	// Publisher is the name of the publisher the principal writes for
+	Publisher string
+}
+
+// PrincipalFromContext returns the principal the authentication middleware stored in ctx
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(*Principal)
+	return principal, ok
+}
+
+// errUnauthenticated is returned when a request carries no credentials
+var errUnauthenticated = fmt.Errorf("authentication required, send an X-API-Key header or a bearer token")
+
+// authenticator checks the credentials of a request against the auth configuration
+type authenticator struct {
+	auth config.Auth
+	now  func() time.Time
+}
+
+// newAuthenticator creates an authenticator of the configured keys and token secret
+func newAuthenticator(auth config.Auth) *authenticator {
+	return &authenticator{auth: auth, now: time.Now}
+}
+
+// middleware refuses requests without valid credentials with 401 and stores the principal of the others
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		principal, err := a.authenticate(req)
+		if err != nil {
+			wr.Header().Set("WWW-Authenticate", `Bearer realm="article"`)
+			writeResponse(wr, req, errorEncoder(req), http.StatusUnauthorized, errorResponse(err, http.StatusUnauthorized))
+			return
+		}
+		next.ServeHTTP(wr, req.WithContext(context.WithValue(req.Context(), principalKey, principal)))
+	})
+}
+
+// authenticate returns the principal of the X-API-Key header or the bearer token of the request
+func (a *authenticator) authenticate(req *http.Request) (*Principal, error) {
+	if key := req.Header.Get("X-API-Key"); key != "" {
+		return a.apiKey(key)
+	}
+	header := req.Header.Get("Authorization")
+	if header == "" {
+		return nil, errUnauthenticated
+	}
+	scheme, token := header, ""
+	if i := strings.IndexByte(header, ' '); i >= 0 {
+		scheme, token = header[:i], strings.TrimSpace(header[i+1:])
+	}
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, fmt.Errorf("authorization scheme must be Bearer")
+	}
+	return a.token(token)
+}
+
+// apiKey finds the configured key, every key is compared so the time taken does not tell which one is close
+func (a *authenticator) apiKey(key string) (*Principal, error) {
+	var principal *Principal
+	for _, k := range a.auth.APIKeys {
+		if k.Key != "" && subtle.ConstantTimeCompare([]byte(k.Key), []byte(key)) == 1 {
+			principal = &Principal{Subject: k.Subject, Method: "api_key", Role: Role(k.Role), Publisher: k.Publisher}
+		}
+	}
+	if principal == nil {
+		return nil, fmt.Errorf("invalid api key")
+	}
+	return principal, nil
+}
+
+// claims are the registered claims of a token this API reads, with the role and publisher of the subject
+type claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Role      string   `json:"role"`
+	Publisher string   `json:"publisher"`
+}
+
+// audience is the aud claim, a single string or a list (RFC 7519 section 4.1.3)
+type audience []string
+
+func (aud *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*aud = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*aud = list
+	return nil
+}
+
+// token verifies a JWT signed with HS256 and its time, issuer and audience claims
+func (a *authenticator) token(token string) (*Principal, error) {
+	invalid := fmt.Errorf("invalid bearer token")
+	if a.auth.JWTSecret == "" {
+		return nil, invalid
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		// only HS256 is accepted, never what the token claims to be signed with
+		return nil, invalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid
+	}
+	mac := hmac.New(sha256.New, []byte(a.auth.JWTSecret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, invalid
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil || c.Subject == "" {
+		return nil, invalid
+	}
+	now := a.now()
+	if c.ExpiresAt != nil && now.After(time.Unix(*c.ExpiresAt, 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("bearer token expired")
+	}
+	if c.NotBefore != nil && now.Before(time.Unix(*c.NotBefore, 0).Add(-clockSkew)) {
+		return nil, fmt.Errorf("bearer token not valid yet")
+	}
+	if a.auth.JWTIssuer != "" && c.Issuer != a.auth.JWTIssuer {
+		return nil, invalid
+	}
+	if a.auth.JWTAudience != "" && !c.Audience.contains(a.auth.JWTAudience) {
+		return nil, invalid
+	}
+	return &Principal{Subject: c.Subject, Method: "jwt", Role: Role(c.Role), Publisher: c.Publisher}, nil
+}
+
+// contains reports whether the audience lists name
+func (aud audience) contains(name string) bool {
+	for _, a := range aud {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSegment decodes a base64url json segment of a token into v
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/femonofsky/articleMaker/article/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signToken makes a JWT of the header and claims signed with HS256
+func signToken(secret string, header, claims map[string]interface{}) string {
+	segment := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	unsigned := segment(header) + "." + segment(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Date(2020, time.February, 25, 19, 2, 35, 0, time.UTC)
+	a := newAuthenticator(config.Auth{
+		APIKeys:     []config.APIKey{{Key: "key-one", Subject: "one"}, {Key: "key-two", Subject: "two"}},
+		JWTSecret:   testSecret,
+		JWTIssuer:   "articles",
+		JWTAudience: "article-api",
+	})
+	a.now = func() time.Time { return now }
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	valid := map[string]interface{}{"sub": "femi", "iss": "articles", "aud": []string{"article-api"}, "exp": now.Add(time.Hour).Unix()}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := map[string]interface{}{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   *Principal
+	}{
+		{"case 01", "X-API-Key", "key-two", &Principal{Subject: "two", Method: "api_key"}},
+		{"case 02", "X-API-Key", "key-three", nil},
+		{"case 03", "Authorization", "Bearer " + signToken(testSecret, hs256, valid), &Principal{Subject: "femi", Method: "jwt"}},
+		{"case 04", "Authorization", "bearer " + signToken(testSecret, hs256, with("aud", "article-api")), &Principal{Subject: "femi", Method: "jwt"}},
+		{"case 05", "Authorization", "Bearer " + signToken("other-secret", hs256, valid), nil},
+		{"case 06", "Authorization", "Bearer " + signToken(testSecret, map[string]interface{}{"alg": "none"}, valid), nil},
+		{"case 07", "Authorization", "Bearer " + signToken(testSecret, hs256, with("exp", now.Add(-time.Hour).Unix())), nil},
+		{"case 08", "Authorization", "Bearer " + signToken(testSecret, hs256, with("nbf", now.Add(time.Hour).Unix())), nil},
+		{"case 09", "Authorization", "Bearer " + signToken(testSecret, hs256, with("iss", "someone")), nil},
+		{"case 10", "Authorization", "Bearer " + signToken(testSecret, hs256, with("aud", "other-api")), nil},
+		{"case 11", "Authorization", "Bearer " + signToken(testSecret, hs256, with("sub", nil)), nil},
+		{"case 12", "Authorization", "Basic Zm9vOmJhcg==", nil},
+		{"case 13", "Authorization", "Bearer not.a.token", nil},
+		{"case 14", "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/article", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			got, err := a.authenticate(req)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("authenticate() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil || *got != *tt.want {
+				t.Errorf("authenticate() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	// no withAPIKey, requests only carry the credentials of the test case
+	srv := httptest.NewServer(New(nil, &testConfig, testStore, nil))
+	defer srv.Close()
+	token := signToken(testSecret, map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "femi", "role": "admin"})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"case 01", http.MethodGet, "/v1/article", "", "", http.StatusOK},
+		{"case 02", http.MethodPost, "/v1/category", "", "", http.StatusUnauthorized},
+		{"case 03", http.MethodPost, "/v1/category", "X-API-Key", "wrong", http.StatusUnauthorized},
+		{"case 04", http.MethodPost, "/v1/category", "X-API-Key", testAPIKey, http.StatusCreated},
+		{"case 05", http.MethodPost, "/category", "Authorization", "Bearer " + token, http.StatusConflict},
+		{"case 06", http.MethodDelete, "/v1/article/1", "", "", http.StatusUnauthorized},
+		{"case 07", http.MethodPut, "/v1/category/1", "", "", http.StatusUnauthorized},
+		{"case 08", http.MethodPatch, "/v1/article/1", "", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(`{"name": "Auth"}`))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			if tt.want == http.StatusUnauthorized && res.Header.Get("WWW-Authenticate") == "" {
+				t.Errorf("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	var got *Principal
+	handler := newAuthenticator(testConfig.Auth).middleware(http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		got, _ = PrincipalFromContext(req.Context())
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/v1/article", nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if got == nil || got.Subject != "tester" || got.Method != "api_key" {
+		t.Errorf("PrincipalFromContext() = %+v", got)
+	}
+	if _, ok := PrincipalFromContext(req.Context()); ok {
+		t.Errorf("principal found in a request that was not authenticated")
+	}
+}
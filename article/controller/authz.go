@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Role of a principal, it decides which articles the principal may change
+type Role string
+
+const (
+	// RoleAdmin may change every article
+	RoleAdmin Role = "admin"
+	// RolePublisher may change the articles of its own publisher
+	RolePublisher Role = "publisher"
+)
+
+// Permission is an action a role allows
+type Permission int
+
+const (
+	// WriteOwnArticles allows creating, changing and deleting the articles of the principal's publisher
+	WriteOwnArticles Permission = iota
+	// WriteAllArticles allows creating, changing and deleting the articles of every publisher
+	WriteAllArticles
+)
+
+// rolePermissions lists the permissions of each role, a principal without a known role has none
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:     {WriteOwnArticles, WriteAllArticles},
+	RolePublisher: {WriteOwnArticles},
+}
+
+// Can reports whether the role of the principal grants perm
+func (p *Principal) Can(perm Permission) bool {
+	for _, granted := range rolePermissions[p.Role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// errForbidden is returned when the caller may not change the articles of a publisher
+var errForbidden = fmt.Errorf("not allowed to change the articles of this publisher")
+
+// authorizeArticles returns errForbidden unless the principal of the request may write articles of every publisher given
+func authorizeArticles(r *http.Request, publishers ...string) error {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return errForbidden
+	}
+	if principal.Can(WriteAllArticles) {
+		return nil
+	}
+	if !principal.Can(WriteOwnArticles) || principal.Publisher == "" {
+		return errForbidden
+	}
+	for _, name := range publishers {
+		if name != principal.Publisher {
+			return errForbidden
+		}
+	}
+	return nil
+}
+
+// errForbiddenRecord is returned when the caller may not change a category or publisher
+var errForbiddenRecord = fmt.Errorf("not allowed to change this record")
+
+// authorizeShared returns errForbiddenRecord unless the principal of the request may write articles of every publisher.
+// Categories and publishers are shared by the articles of every publisher, only such callers create, rename and delete them
+func authorizeShared(r *http.Request) error {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok || !principal.Can(WriteAllArticles) {
+		return errForbiddenRecord
+	}
+	return nil
+}
+
+// authorizePublisher returns errForbiddenRecord unless the principal of the request may replace the publisher current with
+// one named name. A publisher can change the profile of its own record but not its name, its articles would follow it
+func authorizePublisher(r *http.Request, current, name string) error {
+	if authorizeShared(r) == nil {
+		return nil
+	}
+	if authorizeArticles(r, current) != nil || name != current {
+		return errForbiddenRecord
+	}
+	return nil
+}
+
+// ownPublisher fills in the publisher of the principal when the article has none
+func ownPublisher(r *http.Request, publisherName *string) {
+	if principal, ok := PrincipalFromContext(r.Context()); ok && *publisherName == "" {
+		*publisherName = principal.Publisher
+	}
+}
@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthorizeArticles(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *Principal
+		publishers []string
+		want       error
+	}{
+		{"case 01", &Principal{Role: RoleAdmin}, []string{"Tunde", "Femonofsky"}, nil},
+		{"case 02", &Principal{Role: RolePublisher, Publisher: "Tunde"}, []string{"Tunde", "Tunde"}, nil},
+		{"case 03", &Principal{Role: RolePublisher, Publisher: "Tunde"}, []string{"Tunde", "Femonofsky"}, errForbidden},
+		{"case 04", &Principal{Role: RolePublisher}, []string{""}, errForbidden},
+		{"case 05", &Principal{Role: "reader", Publisher: "Tunde"}, []string{"Tunde"}, errForbidden},
+		{"case 06", nil, []string{"Tunde"}, errForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/article", nil)
+			if tt.principal != nil {
+				req = req.WithContext(context.WithValue(req.Context(), principalKey, tt.principal))
+			}
+			if err := authorizeArticles(req, tt.publishers...); err != tt.want {
+				t.Errorf("authorizeArticles() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestArticleAuthorization(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	// the article tests of controller_test.go build on each other from an empty database
+	defer refreshAllTable(testDB)
+	for _, article := range []string{
+		`{"title": "Own article", "body": "Andela", "category": "Extras", "publisher": "Femonofsky"}`,
+		`{"title": "Other article", "body": "Andela", "category": "Extras", "publisher": "Tunde"}`,
+	} {
+		res, err := http.Post(server.URL+"/v1/article", "application/json", strings.NewReader(article))
+		if err != nil || res.StatusCode != http.StatusCreated {
+			t.Fatalf("could not create article: %v", err)
+		}
+	}
+	hs256 := map[string]interface{}{"alg": "HS256"}
+	publisher := "Bearer " + signToken(testSecret, hs256, map[string]interface{}{"sub": "femi", "role": "publisher", "publisher": "Femonofsky"})
+	admin := "Bearer " + signToken(testSecret, hs256, map[string]interface{}{"sub": "editor", "role": "admin"})
+	noRole := "Bearer " + signToken(testSecret, hs256, map[string]interface{}{"sub": "someone"})
+
+	tests := []struct {
+		name      string
+		token     string
+		method    string
+		path      string
+		body      string
+		want      int
+		publisher string
+	}{
+		{"case 01", publisher, http.MethodPost, "/v1/article",
+			`{"title": "New article", "body": "Andela", "category": "Extras", "publisher": "Tunde"}`, http.StatusForbidden, ""},
+		{"case 02", publisher, http.MethodPost, "/v1/article",
+			`{"title": "New article", "body": "Andela", "category": "Extras"}`, http.StatusCreated, "Femonofsky"},
+		{"case 03", publisher, http.MethodPut, "/v1/article/1",
+			`{"title": "Own article", "body": "Money", "category": "Extras", "publisher": "Femonofsky"}`, http.StatusOK, "Femonofsky"},
+		{"case 04", publisher, http.MethodPut, "/v1/article/1",
+			`{"title": "Own article", "body": "Money", "category": "Extras", "publisher": "Tunde"}`, http.StatusForbidden, ""},
+		{"case 05", publisher, http.MethodPut, "/v1/article/2",
+			`{"title": "Other article", "body": "Money", "category": "Extras", "publisher": "Tunde"}`, http.StatusForbidden, ""},
+		{"case 06", publisher, http.MethodPatch, "/v1/article/2", `{"body": "Money"}`, http.StatusForbidden, ""},
+		{"case 07", publisher, http.MethodPatch, "/v1/article/1", `{"publisher": "Tunde"}`, http.StatusForbidden, ""},
+		{"case 08", publisher, http.MethodDelete, "/v1/article/2", ``, http.StatusForbidden, ""},
+		{"case 09", noRole, http.MethodPost, "/v1/article",
+			`{"title": "Stray article", "body": "Andela", "category": "Extras", "publisher": "Tunde"}`, http.StatusForbidden, ""},
+		{"case 10", admin, http.MethodPatch, "/v1/article/2", `{"body": "Money"}`, http.StatusOK, "Tunde"},
+		{"case 11", publisher, http.MethodPut, "/v1/article/1",
+			`{"title": "Own article", "body": "Money", "category": "Extras"}`, http.StatusOK, "Femonofsky"},
+		{"case 12", publisher, http.MethodPut, "/v1/article/1", `{"body": "Money", "category": "Extras"}`, http.StatusUnprocessableEntity, ""},
+		{"case 13", publisher, http.MethodPatch, "/v1/article/1", `{"publisher": null}`, http.StatusOK, "Femonofsky"},
+		{"case 14", publisher, http.MethodDelete, "/v1/article/1", ``, http.StatusNoContent, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			req.Header.Set("Authorization", tt.token)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			if res.StatusCode == http.StatusNoContent {
+				return
+			}
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+			if tt.want == http.StatusForbidden && body.Data["code"] != "forbidden" {
+				t.Errorf("code = %v, want forbidden", body.Data["code"])
+			}
+			if tt.publisher != "" && body.Data["publisher"] != tt.publisher {
+				t.Errorf("publisher = %v, want %v", body.Data["publisher"], tt.publisher)
+			}
+		})
+	}
+
+	t.Run("bulk", func(t *testing.T) {
+		batch := fmt.Sprintf("[%s,%s]",
+			`{"title": "Bulk own", "body": "Andela", "category": "Extras"}`,
+			`{"title": "Bulk other", "body": "Andela", "category": "Extras", "publisher": "Tunde"}`)
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/article/bulk?mode=best_effort", strings.NewReader(batch))
+		if err != nil {
+			t.Fatalf("could not create request: %v", err)
+		}
+		req.Header.Set("Authorization", publisher)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("could not send request: %v", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusMultiStatus {
+			t.Fatalf("expected status %v; got %v", http.StatusMultiStatus, res.Status)
+		}
+		var body struct {
+			Data bulkResult `json:"data"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+		items := body.Data.Items
+		if len(items) != 2 || !items[0].Success || items[1].Error == nil || items[1].Error.Code != "forbidden" {
+			t.Errorf("unexpected items %+v", items)
+		}
+	})
+}
+
+func TestRecordAuthorization(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	defer refreshAllTable(testDB)
+	// category 1 Extras, publishers 1 Femonofsky and 2 Tunde
+	for _, article := range []string{
+		`{"title": "Own article", "body": "Andela", "category": "Extras", "publisher": "Femonofsky"}`,
+		`{"title": "Other article", "body": "Andela", "category": "Extras", "publisher": "Tunde"}`,
+	} {
+		res, err := http.Post(server.URL+"/v1/article", "application/json", strings.NewReader(article))
+		if err != nil || res.StatusCode != http.StatusCreated {
+			t.Fatalf("could not create article: %v", err)
+		}
+	}
+	hs256 := map[string]interface{}{"alg": "HS256"}
+	publisher := "Bearer " + signToken(testSecret, hs256, map[string]interface{}{"sub": "femi", "role": "publisher", "publisher": "Femonofsky"})
+	admin := "Bearer " + signToken(testSecret, hs256, map[string]interface{}{"sub": "editor", "role": "admin"})
+	noRole := "Bearer " + signToken(testSecret, hs256, map[string]interface{}{"sub": "someone"})
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"case 01", publisher, http.MethodPost, "/v1/category", `{"name": "News"}`, http.StatusForbidden},
+		{"case 02", noRole, http.MethodPut, "/v1/category/1", `{"name": "Hijacked"}`, http.StatusForbidden},
+		{"case 03", publisher, http.MethodDelete, "/v1/category/1", ``, http.StatusForbidden},
+		{"case 04", publisher, http.MethodPost, "/v1/publisher", `{"name": "Other"}`, http.StatusForbidden},
+		{"case 05", publisher, http.MethodPut, "/v1/publisher/2", `{"name": "Hijacked"}`, http.StatusForbidden},
+		{"case 06", publisher, http.MethodPut, "/v1/publisher/2", `{"name": "Tunde", "contact_email": "me@example.com"}`, http.StatusForbidden},
+		{"case 07", publisher, http.MethodPut, "/v1/publisher/1", `{"name": "Renamed"}`, http.StatusForbidden},
+		{"case 08", publisher, http.MethodPut, "/v1/publisher/1", `{"name": "Femonofsky", "bio": "Writes"}`, http.StatusOK},
+		{"case 09", publisher, http.MethodPut, "/v1/publisher/99", `{"name": "Femonofsky"}`, http.StatusNotFound},
+		{"case 10", publisher, http.MethodDelete, "/v1/publisher/2", ``, http.StatusForbidden},
+		{"case 11", noRole, http.MethodPut, "/v1/publisher/1", `{"name": "Femonofsky"}`, http.StatusForbidden},
+		{"case 12", admin, http.MethodPut, "/v1/category/1", `{"name": "News"}`, http.StatusOK},
+		{"case 13", admin, http.MethodPut, "/v1/publisher/2", `{"name": "Tunde Ade"}`, http.StatusOK},
+		{"case 14", admin, http.MethodPost, "/v1/publisher", `{"name": "Other"}`, http.StatusCreated},
+		{"case 15", admin, http.MethodDelete, "/v1/publisher/3", ``, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			req.Header.Set("Authorization", tt.token)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			if tt.want != http.StatusForbidden {
+				return
+			}
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+			if body.Data["code"] != "forbidden" {
+				t.Errorf("code = %v, want forbidden", body.Data["code"])
+			}
+		})
+	}
+
+	// the refused changes left the records and their articles as they were
+	article, err := testStore.GetArticle(2)
+	if err != nil || article.CategoryName != "News" || article.PublisherName != "Tunde Ade" {
+		t.Errorf("GetArticle(2) = %+v, %v; want category News and publisher Tunde Ade", article, err)
+	}
+	other, err := testStore.GetPublisher(2)
+	if err != nil || other.ContactEmail != "" {
+		t.Errorf("GetPublisher(2) = %+v, %v; want no contact email", other, err)
+	}
+}
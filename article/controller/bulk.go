@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/model"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const (
+	// maxBulkArticles caps the number of articles of one bulk import
+	maxBulkArticles = 10000
+	// maxBulkBytes caps the size of the body of one bulk import, a line or element is buffered whole while it is decoded
+	maxBulkBytes = 32 << 20
+	// ndjsonType is the media type of a newline delimited json stream, one article per line
+	ndjsonType = "application/x-ndjson"
+	// atomicMode stores nothing unless every article of the batch can be stored
+	atomicMode = "atomic"
+	// bestEffortMode stores every valid article of the batch
+	bestEffortMode = "best_effort"
+)
+
+// bulkItem is the outcome of one article of a bulk import, index is its position in the request
+type bulkItem struct {
+	Index   int            `json:"index"`
+	Success bool           `json:"success"`
+	Article *model.Article `json:"article,omitempty"`
+	Error   *errorBody     `json:"error,omitempty"`
+}
+
+// bulkResult is the data of a bulk import response
+type bulkResult struct {
+	Mode    string     `json:"mode"`
+	Created int        `json:"created"`
+	Failed  int        `json:"failed"`
+	Items   []bulkItem `json:"items"`
+}
+
+// bulkError is returned when an atomic import stored nothing, its error body lists every article
+type bulkError struct {
+	result bulkResult
+}
+
+func (e *bulkError) Error() string {
+	return fmt.Sprintf("%d of %d articles failed, nothing was imported", e.result.Failed, len(e.result.Items))
+}
+
+// newBulkResult pairs the articles of a batch with the errors of their import
+func newBulkResult(mode string, articles model.Articles, errs []error) bulkResult {
+	result := bulkResult{Mode: mode, Items: make([]bulkItem, len(articles))}
+	for i, err := range errs {
+		item := bulkItem{Index: i, Success: err == nil}
+		if err != nil {
+			status := http.StatusBadRequest
+			switch err {
+			case model.ErrImportAborted:
+				status = http.StatusFailedDependency
+			case errForbidden:
+				status = http.StatusForbidden
+			}
+			body := newErrorBody(err, errorStatus(err, status))
+			item.Error = &body
+			result.Failed++
+		} else {
+			item.Article = articles[i]
+			result.Created++
+		}
+		result.Items[i] = item
+	}
+	return result
+}
+
+// bulkBody is a body limited by http.MaxBytesReader, it remembers whether the limit failed a read
+type bulkBody struct {
+	io.ReadCloser
+	read     int64
+	tooLarge bool
+}
+
+func (b *bulkBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	// MaxBytesReader fails the read after the last byte it allows
+	if err != nil && err != io.EOF && b.read >= maxBulkBytes {
+		b.tooLarge = true
+	}
+	return n, err
+}
+
+// readError is the status and error of a body that could not be read or decoded,
+// 413 once the body went over maxBulkBytes
+func (b *bulkBody) readError(err error) (int, error) {
+	if b.tooLarge {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("a bulk import takes at most %d bytes", maxBulkBytes)
+	}
+	return http.StatusBadRequest, err
+}
+
+// decodeBulk reads the articles of a bulk import, a json array or a NDJSON stream by the Content-Type.
+// An article that cannot be decoded is left nil and its error is kept at the same index,
+// a body that is not an array, holds too many articles or is over maxBulkBytes fails as a whole
+func decodeBulk(w io.Writer, r *http.Request) (model.Articles, []error, int, error) {
+	ndjson := false
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		switch {
+		case err == nil && (mediaType == ndjsonType || mediaType == "application/ndjson"):
+			ndjson = true
+		case err != nil || mediaType != "application/json":
+			return nil, nil, http.StatusUnsupportedMediaType, fmt.Errorf("content type must be application/json or %v got: %v", ndjsonType, contentType)
+		}
+	}
+	// a nil writer only skips closing the connection once the limit is hit
+	wr, _ := w.(http.ResponseWriter)
+	body := &bulkBody{ReadCloser: http.MaxBytesReader(wr, r.Body, maxBulkBytes)}
+	defer body.Close()
+
+	var articles model.Articles
+	var errs []error
+	add := func(data []byte) error {
+		if len(articles) == maxBulkArticles {
+			return fmt.Errorf("a bulk import takes at most %d articles", maxBulkArticles)
+		}
+		article := &model.Article{}
+		err := json.Unmarshal(data, article)
+		if err != nil {
+			if _, ok := err.(*model.ValidationError); !ok {
+				err = fmt.Errorf("unable to decode article: %v", err)
+			}
+			article = nil
+		}
+		articles = append(articles, article)
+		errs = append(errs, err)
+		return nil
+	}
+
+	if ndjson {
+		reader := bufio.NewReader(body)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				if err := add(line); err != nil {
+					return nil, nil, http.StatusRequestEntityTooLarge, err
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				status, err := body.readError(fmt.Errorf("unable to read request body: %v", err))
+				return nil, nil, status, err
+			}
+		}
+	} else {
+		dec := json.NewDecoder(body)
+		if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+			status, err := body.readError(fmt.Errorf("request body must be a json array of articles"))
+			return nil, nil, status, err
+		}
+		for dec.More() {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				status, err := body.readError(fmt.Errorf("unable to decode json request body: %v", err))
+				return nil, nil, status, err
+			}
+			if err := add(raw); err != nil {
+				return nil, nil, http.StatusRequestEntityTooLarge, err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			status, err := body.readError(fmt.Errorf("unable to decode json request body: %v", err))
+			return nil, nil, status, err
+		}
+	}
+
+	if len(articles) == 0 {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("no articles to import")
+	}
+	return articles, errs, http.StatusOK, nil
+}
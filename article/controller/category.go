@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/model"
+	"github.com/gorilla/mux"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// CategoryController Handler
+type CategoryController struct {
+	logger *log.Logger
+	store  model.Store
+}
+
+// GetAll Handler: list all categories
+func (cc *CategoryController) GetAll(w io.Writer, r *http.Request) (interface{}, int, error) {
+	categories, err := cc.store.WithContext(r.Context()).GetCategories()
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return categories, http.StatusOK, nil
+}
+
+// Get Handler: Get category using ID
+func (cc *CategoryController) Get(w io.Writer, r *http.Request) (interface{}, int, error) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v", vars["id"])
+	}
+
+	category, err := cc.store.WithContext(r.Context()).GetCategory(id)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	return category, http.StatusOK, nil
+}
+
+// Create Handler: Create a new Category, only callers that may change every article can
+func (cc *CategoryController) Create(w io.Writer, r *http.Request) (interface{}, int, error) {
+	if err := authorizeShared(r); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	category, err := model.SerializeCategory(r.Body)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err = category.Validate(); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if err = cc.store.WithContext(r.Context()).CreateCategory(category); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return category, http.StatusCreated, nil
+}
+
+// Put Handler: rename a category, its articles follow the new name. Only callers that may change every article can
+func (cc *CategoryController) Put(w io.Writer, r *http.Request) (interface{}, int, error) {
+	if err := authorizeShared(r); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v", vars["id"])
+	}
+	category, err := model.SerializeCategory(r.Body)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err = category.Validate(); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if err = cc.store.WithContext(r.Context()).UpdateCategory(id, category); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return category, http.StatusOK, nil
+}
+
+// Delete Handler: delete category by ID, refused while it still has articles. Only callers that may change every article can
+func (cc *CategoryController) Delete(w io.Writer, r *http.Request) (interface{}, int, error) {
+	if err := authorizeShared(r); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v", vars["id"])
+	}
+
+	if err = cc.store.WithContext(r.Context()).DeleteCategory(id); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return nil, http.StatusNoContent, nil
+}
+
+// newCategory creates a new Category Handle of the categories of store
+func newCategory(logger *log.Logger, store model.Store) *CategoryController {
+	return &CategoryController{logger: logger, store: store}
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"github.com/femonofsky/articleMaker/article/model"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// setETag sends the ETag of the article when w is the http.ResponseWriter
+func setETag(w io.Writer, article *model.Article) {
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rw.Header().Set("ETag", article.ETag())
+	}
+}
+
+// ifMatch reports whether the If-Match header of the request allows writing the article,
+// a request without the header always matches
+func ifMatch(r *http.Request, article *model.Article) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	return etagMatches(header, article.ETag(), false)
+}
+
+// ifNoneMatch reports whether the If-None-Match header of the request lists the article version
+func ifNoneMatch(r *http.Request, article *model.Article) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	return etagMatches(header, article.ETag(), true)
+}
+
+// etagMatches checks a comma separated list of entity tags against etag,
+// weak comparison ignores the W/ prefix (RFC 7232 section 2.3.2)
+func etagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
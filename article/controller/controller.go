@@ -1,68 +1,265 @@
 package controller
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/config"
+	"github.com/femonofsky/articleMaker/article/metrics"
+	"github.com/femonofsky/articleMaker/article/model"
 	"github.com/gorilla/mux"
 	"io"
 	"log"
 	"net/http"
+	"strings"
+	"time"
+)
+
+// contextKey keys the values this package stores in a request context
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	requestIDKey
+	loggerKey
 )
 
 // Custom struct for response
 type response struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data"`
+	Success    bool        `json:"success"`
+	Data       interface{} `json:"data"`
+	Pagination *pagination `json:"pagination,omitempty"`
 }
 
-// Register all Controllers and its Routes
-func New(logger *log.Logger) *mux.Router {
+// errorBody is the data of a failed response
+type errorBody struct {
+	Code    string             `json:"code"`
+	Message string             `json:"message"`
+	Fields  []model.FieldError `json:"fields,omitempty"`
+	Items   []bulkItem         `json:"items,omitempty"`
+}
 
-	// Initializing Article Handler
-	articleHandle := newArticle(logger)
+// newErrorBody describes err with a machine readable code,
+// validation errors also list every field that failed by its json name
+// and failed bulk imports the outcome of every article
+func newErrorBody(err error, status int) errorBody {
+	if verr, ok := err.(*model.ValidationError); ok {
+		return errorBody{Code: "validation_failed", Message: verr.Error(), Fields: verr.Fields}
+	}
+	if berr, ok := err.(*bulkError); ok {
+		return errorBody{Code: "bulk_import_failed", Message: berr.Error(), Items: berr.result.Items}
+	}
+	code := strings.ToLower(strings.Replace(http.StatusText(status), " ", "_", -1))
+	return errorBody{Code: code, Message: err.Error()}
+}
+
+// errorStatus maps the kind of a model error to its status, other errors keep the status of the handler
+func errorStatus(err error, status int) int {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrArticleChanged):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, model.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, model.ErrStorage):
+		return http.StatusInternalServerError
+	case errors.Is(err, model.ErrCanceled):
+		return http.StatusServiceUnavailable
+	}
+	return status
+}
+
+// methodNotAllowed answers a request whose path has routes for other methods only
+func methodNotAllowed(wr http.ResponseWriter, req *http.Request) {
+	http.Error(wr, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// Register all Controllers and its Routes on the records of store, the routes that change data need
+// the credentials of cfg.Auth and browsers of other origins need cfg.CORS.
+// Every request is logged to logger, or to stderr when it is nil.
+// /readyz reports the lifecycle of health, a nil health is always ready
+func New(logger *log.Logger, cfg *config.Config, store model.Store, health *Health) *mux.Router {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	if logger == nil {
+		logger = fallbackLogger
+	}
+	if health == nil {
+		health = NewHealth(store)
+		health.Ready()
+	}
+	h := newHandlers(logger, cfg, store)
 
 	// create a new serve mux and register handlers
 	sm := mux.NewRouter()
+	cors := newCORS(cfg.CORS)
+	sm.Use(accessLog(logger), instrument, handlerTimeout(time.Duration(cfg.Server.WithDefaults().HandlerTimeout)), cors.middleware)
+	// mux skips the middlewares when no route matches the path or the method, these are wrapped the same way
+	sm.NotFoundHandler = accessLog(logger)(instrument(cors.middleware(http.NotFoundHandler())))
+	sm.MethodNotAllowedHandler = accessLog(logger)(instrument(cors.middleware(http.HandlerFunc(methodNotAllowed))))
 
-	// Handle All GET
-	getRouter := sm.Methods(http.MethodGet).Subrouter()
-	getRouter.HandleFunc("/article/", responseHandler(articleHandle.GetAll))
-	getRouter.HandleFunc("/article", responseHandler(articleHandle.GetAll))
-	getRouter.HandleFunc("/article/{id:[0-9)]+}", responseHandler(articleHandle.Get))
-	getRouter.HandleFunc("/article/{id:[0-9)]+}/", responseHandler(articleHandle.Get))
+	// The probes of the orchestrator and the Prometheus scrape are not versioned, limited nor authenticated
+	sm.HandleFunc("/healthz", health.live).Methods(http.MethodGet)
+	sm.HandleFunc("/readyz", health.ready).Methods(http.MethodGet)
+	sm.Handle("/metrics", metrics.Default).Methods(http.MethodGet)
 
-	// Handle All PUT
-	putRouter := sm.Methods(http.MethodPut).Subrouter()
-	putRouter.HandleFunc("/article/{id:[0-9)]+}", responseHandler(articleHandle.Put))
-	putRouter.HandleFunc("/article/{id:[0-9)]+}/", responseHandler(articleHandle.Put))
+	// Preflight requests are answered before the routes of the versions, without authentication or rate limits
+	sm.Methods(http.MethodOptions).MatcherFunc(isPreflight(sm)).HandlerFunc(cors.preflight(sm))
 
-	// Handle All POST
-	postRouter := sm.Methods(http.MethodPost).Subrouter()
-	postRouter.HandleFunc("/article/", responseHandler(articleHandle.Create))
-	postRouter.HandleFunc("/article", responseHandler(articleHandle.Create))
+	// Each version is mounted under its prefix, a new version registers its routes beside v1 with the same handlers
+	registerV1(sm.PathPrefix("/v1").Subrouter(), h)
 
-	// Handle All DELETE
-	deleteRouter := sm.Methods(http.MethodDelete).Subrouter()
-	deleteRouter.HandleFunc("/article/{id:[0-9)]+}", responseHandler(articleHandle.Delete))
-	deleteRouter.HandleFunc("/article/{id:[0-9)]+}/", responseHandler(articleHandle.Delete))
+	// The bare paths serve v1 for the clients that predate versioning, until legacySunset
+	legacy := sm.NewRoute().Subrouter()
+	legacy.Use(deprecated("/v1"))
+	registerV1(legacy, h)
 
 	return sm
 }
 
-// responseHandler format response into json and also handle error
+// responseHandler format response into the negotiated format and also handle error
 func responseHandler(h func(io.Writer, *http.Request) (interface{}, int, error)) http.HandlerFunc {
+	return negotiated(h, false)
+}
+
+// listHandler is the responseHandler of a route answering a list, the only data csv can write
+func listHandler(h func(io.Writer, *http.Request) (interface{}, int, error)) http.HandlerFunc {
+	return negotiated(h, true)
+}
+
+// negotiated answers 406 before h runs when no acceptable format can write its response,
+// so a request is never carried out to then fail on its format
+func negotiated(h func(io.Writer, *http.Request) (interface{}, int, error), list bool) http.HandlerFunc {
 	return func(wr http.ResponseWriter, req *http.Request) {
-		// Add Cors
-		wr.Header().Set("Access-Control-Allow-Origin", "*")
+		wr.Header().Add("Vary", "Accept")
+		enc, ok := negotiate(req, list)
+		if !ok {
+			err := fmt.Errorf("supported formats are json, xml, yaml and csv for lists")
+			writeResponse(wr, req, jsonEncoder{}, http.StatusNotAcceptable, errorResponse(err, http.StatusNotAcceptable))
+			return
+		}
+
 		data, status, err := h(wr, req)
 		if err != nil {
-			data = err.Error()
+			status = errorStatus(err, status)
+			if status >= http.StatusInternalServerError {
+				logFailure(req, err)
+			}
+			writeResponse(wr, req, errorEncoder(req), status, errorResponse(err, status))
+			return
 		}
-		wr.Header().Set("Content-Type", "application/json")
-		wr.WriteHeader(status)
-		err = json.NewEncoder(wr).Encode(response{Data: data, Success: err == nil})
+		res := response{Data: data, Success: true}
+		if pg, ok := data.(*page); ok {
+			res.Data = pg.items
+			res.Pagination = &pg.pagination
+		}
+		if status == http.StatusNoContent || status == http.StatusNotModified {
+			// these statuses must not carry a body
+			wr.WriteHeader(status)
+			return
+		}
+		writeResponse(wr, req, enc, status, res)
+	}
+}
+
+// streaming is the handler of a route that streams its body, handlerTimeout lets it run as long as it writes
+type streaming http.HandlerFunc
+
+func (s streaming) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
+	s(wr, req)
+}
+
+// handlerTimeout gives the requests of the routes that are not streaming a deadline of timeout.
+// The handlers bind the store to the request, past the deadline it answers ErrCanceled, a 503,
+// and rolls back the change in progress. The handler still runs until the store returns, so the
+// server waits for it when it shuts down. The server has no write timeout by default as it would
+// cut off a long stream after its status was sent
+func handlerTimeout(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+			if route := mux.CurrentRoute(req); route != nil {
+				if _, ok := route.GetHandler().(streaming); ok {
+					next.ServeHTTP(wr, req)
+					return
+				}
+			}
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(wr, req.WithContext(ctx))
+		})
+	}
+}
+
+// streamHandler serves a handler that returns a function writing its body as NDJSON instead of data to encode.
+// Errors returned before the body starts get the usual error response
+func streamHandler(h func(io.Writer, *http.Request) (func(io.Writer) error, int, error)) streaming {
+	return func(wr http.ResponseWriter, req *http.Request) {
+		write, status, err := h(wr, req)
 		if err != nil {
-			log.Printf("could not encode response to output: %v", err)
+			status = errorStatus(err, status)
+			if status >= http.StatusInternalServerError {
+				logFailure(req, err)
+			}
+			writeResponse(wr, req, jsonEncoder{}, status, errorResponse(err, status))
+			return
+		}
+		wr.Header().Set("Content-Type", ndjsonType)
+		wr.WriteHeader(status)
+		if err := write(wr); err != nil {
+			// the status is already sent, the client sees the stream end early
+			logFailure(req, err)
 		}
+	}
+}
+
+// logFailure logs the cause of a failed request to its logger, the client only sees the kind of error
+func logFailure(req *http.Request, err error) {
+	cause := err
+	var storageErr *model.StorageError
+	if errors.As(err, &storageErr) {
+		cause = storageErr.Err
+	}
+	loggerFor(req, nil).Printf("%s %s failed: %v", req.Method, req.URL.Path, cause)
+}
+
+// errorEncoder picks the encoder of an error response, json when the negotiated format cannot carry it.
+// Errors are not lists, csv clients get them as json
+func errorEncoder(req *http.Request) encoder {
+	enc, ok := negotiate(req, false)
+	if !ok {
+		return jsonEncoder{}
+	}
+	return enc
+}
+
+// errorResponse wraps the error body of err in the response envelope
+func errorResponse(err error, status int) response {
+	return response{Data: newErrorBody(err, status), Success: false}
+}
 
+// writeResponse encodes res before sending the status so an encoding failure can still be reported
+func writeResponse(wr http.ResponseWriter, req *http.Request, enc encoder, status int, res response) {
+	var buf bytes.Buffer
+	err := enc.encode(&buf, res)
+	if err == errNotRepresentable {
+		status = http.StatusNotAcceptable
+		enc, res = jsonEncoder{}, errorResponse(err, status)
+		buf.Reset()
+		err = enc.encode(&buf, res)
+	}
+	if err != nil {
+		loggerFor(req, nil).Printf("could not encode response to output: %v", err)
+		http.Error(wr, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	wr.Header().Set("Content-Type", enc.mediaType())
+	wr.WriteHeader(status)
+	if _, err := buf.WriteTo(wr); err != nil {
+		loggerFor(req, nil).Printf("could not write response to output: %v", err)
 	}
 }
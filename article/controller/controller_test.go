@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/femonofsky/articleMaker/article/config"
 	"github.com/femonofsky/articleMaker/article/model"
@@ -12,17 +14,49 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 var server *httptest.Server
 
+var (
+	// testDB is the database of the tests, refreshed between the tests that need it empty
+	testDB *gorm.DB
+	// testStore keeps the records of the tests in testDB
+	testStore *model.GormStore
+)
+
+const (
+	// testAPIKey is sent by the requests to server that have no credentials
+	testAPIKey = "test-key"
+	// testSecret signs the bearer tokens of the tests
+	testSecret = "test-secret"
+)
+
+// testConfig of the router under test
+var testConfig = config.Config{
+	DB: config.DB{
+		Driver: "sqlite3",
+		Name:   "articleTest.db",
+	},
+	Auth: config.Auth{
+		APIKeys:   []config.APIKey{{Key: testAPIKey, Subject: "tester", Role: string(RoleAdmin)}},
+		JWTSecret: testSecret,
+	},
+}
+
+// withAPIKey authenticates the requests without credentials so tests can focus on the handlers
+func withAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-API-Key") == "" && req.Header.Get("Authorization") == "" {
+			req.Header.Set("X-API-Key", testAPIKey)
+		}
+		next.ServeHTTP(wr, req)
+	})
+}
+
 func TestMain(m *testing.M) {
-	cfg := config.Config{
-		DB: config.DB{
-			Driver: "sqlite3",
-			Name:   "articleTest.db",
-		},
-	}
+	cfg := testConfig
 	log.Println("loading Database")
 	db, err := model.New(&cfg)
 	if err != nil {
@@ -30,9 +64,10 @@ func TestMain(m *testing.M) {
 	}
 	defer db.Close()
 	db.Debug().AutoMigrate(&model.Article{}, &model.Category{}, &model.Publisher{})
+	testDB, testStore = db, model.NewGormStore(db)
 
 	log.Println("Finished loading Database")
-	srv := httptest.NewServer(New(nil))
+	srv := httptest.NewServer(withAPIKey(New(nil, &testConfig, testStore, nil)))
 	defer srv.Close()
 	server = srv
 	if err := refreshAllTable(db); err != nil {
@@ -178,9 +213,12 @@ func TestNewArticleController_Put(t *testing.T) {
 		body    string
 		wantErr bool
 	}{
-		{"case 01", `1`, `{"body": "money in the bank"}`, false},
-		{"case 02", `990`, `{"body": "money in the bank"}`, true},
+		{"case 01", `1`, `{"title": "Tommy test","body": "money in the bank",
+								"category": "Extras","publisher": "Femonofsky"}`, false},
+		{"case 02", `990`, `{"title": "Tommy test","body": "money in the bank",
+								"category": "Extras","publisher": "Femonofsky"}`, true},
 		{"case 03", `2`, `{"title": "Tommy test"}`, true},
+		{"case 04", `1`, `{"body": "money in the bank"}`, true},
 	}
 
 	for _, tt := range tests {
@@ -239,3 +277,946 @@ func TestNewArticleController_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestCategoryController(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := `{ "title": "Category test","body": "Andela is the best office to work in",
+								"category": "Extras","publisher": "Femonofsky"}`
+	res, err := http.Post(fmt.Sprintf("%s/article", server.URL), "application/json", strings.NewReader(article))
+	if err != nil || res.StatusCode != http.StatusCreated {
+		t.Fatalf("could not create article: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"case 01", http.MethodGet, "/category", ``, http.StatusOK},
+		{"case 02", http.MethodGet, "/category/1", ``, http.StatusOK},
+		{"case 03", http.MethodGet, "/category/990", ``, http.StatusNotFound},
+		{"case 04", http.MethodPost, "/category", `{"name": "News"}`, http.StatusCreated},
+		{"case 05", http.MethodPost, "/category", `{"name": "News"}`, http.StatusConflict},
+		{"case 06", http.MethodPost, "/category", `{}`, http.StatusUnprocessableEntity},
+		{"case 07", http.MethodPut, "/category/1", `{"name": "Specials"}`, http.StatusOK},
+		{"case 08", http.MethodPut, "/category/1", `{"name": "News"}`, http.StatusConflict},
+		{"case 09", http.MethodPut, "/category/990", `{"name": "Sport"}`, http.StatusNotFound},
+		{"case 10", http.MethodGet, "/article?category=Specials", ``, http.StatusOK},
+		{"case 11", http.MethodDelete, "/category/1", ``, http.StatusConflict},
+		{"case 12", http.MethodDelete, "/category/2", ``, http.StatusNoContent},
+		{"case 13", http.MethodDelete, "/category/2", ``, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := http.Client{}
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Errorf("expected status %v; got %v", tt.want, res.Status)
+			}
+		})
+	}
+
+	articles, _, err := testStore.GetArticlesPage(model.ArticleFilter{CategoryName: "Specials"}, model.Page{})
+	if err != nil || len(articles) != 1 {
+		t.Errorf("article did not follow category rename got: %v, %v", articles, err)
+	}
+}
+
+func TestPublisherController(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	articles := []string{
+		`{ "title": "Publisher test","body": "Andela is the best office to work in",
+								"category": "Extras","publisher": "Femonofsky"}`,
+		`{ "title": "Publisher news","body": "Andela is the best office to work in",
+								"category": "News","publisher": "Femonofsky"}`,
+	}
+	for _, article := range articles {
+		res, err := http.Post(fmt.Sprintf("%s/article", server.URL), "application/json", strings.NewReader(article))
+		if err != nil || res.StatusCode != http.StatusCreated {
+			t.Fatalf("could not create article: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"case 01", http.MethodGet, "/publisher", ``, http.StatusOK},
+		{"case 02", http.MethodGet, "/publisher/1", ``, http.StatusOK},
+		{"case 03", http.MethodGet, "/publisher/990", ``, http.StatusNotFound},
+		{"case 04", http.MethodPost, "/publisher", `{"name": "Tunde", "website": "https://tunde.dev"}`, http.StatusCreated},
+		{"case 05", http.MethodPost, "/publisher", `{"name": "Sola", "contact_email": "sola"}`, http.StatusUnprocessableEntity},
+		{"case 06", http.MethodPut, "/publisher/1", `{"name": "Femonofsky", "bio": "Writes"}`, http.StatusOK},
+		{"case 07", http.MethodPut, "/publisher/1", `{"name": "Tunde"}`, http.StatusConflict},
+		{"case 08", http.MethodGet, "/publisher/Femonofsky/articles", ``, http.StatusOK},
+		{"case 09", http.MethodGet, "/publisher/Femonofsky/articles?category=News", ``, http.StatusOK},
+		{"case 10", http.MethodGet, "/publisher/Femonofsky/articles?published_at=Tommy", ``, http.StatusBadRequest},
+		{"case 11", http.MethodGet, "/publisher/Nobody/articles", ``, http.StatusNotFound},
+		{"case 12", http.MethodDelete, "/publisher/1", ``, http.StatusConflict},
+		{"case 13", http.MethodDelete, "/publisher/2", ``, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := http.Client{}
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Errorf("expected status %v; got %v", tt.want, res.Status)
+			}
+		})
+	}
+}
+
+func TestNewArticleController_GetAllPaged(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	for i := 0; i < 5; i++ {
+		article := fmt.Sprintf(`{ "title": "Paged %d","body": "Andela is the best office to work in",
+								"category": "Extras","publisher": "Femonofsky"}`, i)
+		res, err := http.Post(fmt.Sprintf("%s/article", server.URL), "application/json", strings.NewReader(article))
+		if err != nil || res.StatusCode != http.StatusCreated {
+			t.Fatalf("could not create article: %v", err)
+		}
+	}
+
+	type pagedResponse struct {
+		Data       []map[string]interface{} `json:"data"`
+		Pagination pagination               `json:"pagination"`
+	}
+	get := func(query string) (*http.Response, pagedResponse) {
+		var body pagedResponse
+		res, err := http.Get(fmt.Sprintf("%s/article%s", server.URL, query))
+		if err != nil {
+			t.Fatalf("could not send GET request: %v", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+		}
+		return res, body
+	}
+
+	res, first := get("?limit=2")
+	if res.StatusCode != http.StatusOK || len(first.Data) != 2 || first.Pagination.Total != 5 {
+		t.Fatalf("unexpected first page %v: %+v", res.Status, first)
+	}
+	if first.Pagination.PrevCursor != "" || first.Pagination.NextCursor == "" {
+		t.Errorf("first page cursors are wrong: %+v", first.Pagination)
+	}
+
+	_, second := get("?limit=2&cursor=" + first.Pagination.NextCursor)
+	if len(second.Data) != 2 || second.Data[0]["title"] != "Paged 2" || second.Pagination.PrevCursor == "" {
+		t.Errorf("unexpected second page: %+v", second)
+	}
+
+	_, sorted := get("?sort=-title&limit=1")
+	if len(sorted.Data) != 1 || sorted.Data[0]["title"] != "Paged 4" {
+		t.Errorf("unexpected sorted page: %+v", sorted)
+	}
+
+	_, ranged := get("?created_after=2000-01-01%2000:00:00&published_before=2000-01-01%2000:00:00")
+	if len(ranged.Data) != 5 {
+		t.Errorf("unexpected date range page: %+v", ranged)
+	}
+
+	_, searched := get("?q=paged+3&publisher=Femonofsky")
+	if len(searched.Data) != 1 || searched.Data[0]["title"] != "Paged 3" {
+		t.Errorf("unexpected search page: %+v", searched)
+	}
+
+	_, last := get("?limit=2&offset=4")
+	if len(last.Data) != 1 || last.Pagination.NextCursor != "" {
+		t.Errorf("unexpected last page: %+v", last)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"case 01", "?limit=0", http.StatusBadRequest},
+		{"case 02", "?limit=two", http.StatusBadRequest},
+		{"case 03", "?offset=-1", http.StatusBadRequest},
+		{"case 04", "?cursor=bm9wZQ", http.StatusBadRequest},
+		{"case 05", "?offset=1&cursor=" + first.Pagination.NextCursor, http.StatusBadRequest},
+		{"case 06", "?limit=1000", http.StatusOK},
+		{"case 07", "?sort=-published_at,title", http.StatusOK},
+		{"case 08", "?sort=body", http.StatusBadRequest},
+		{"case 09", "?published_from=2020-01-01%2000:00:00&published_to=2030-01-01%2000:00:00", http.StatusOK},
+		{"case 10", "?created_after=2020-01-01%2000:00:00&created_before=2020-01-02", http.StatusBadRequest},
+		{"case 11", "?q=andela&category=Extras&sort=title", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _ := get(tt.query)
+			if res.StatusCode != tt.want {
+				t.Errorf("expected status %v; got %v", tt.want, res.Status)
+			}
+		})
+	}
+}
+
+func TestNewArticleController_Patch(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	articles := []string{
+		`{ "title": "Patch test","body": "Andela is the best office to work in",
+								"category": "Extras","publisher": "Femonofsky","published_at": "2020-02-25 19:02:35"}`,
+		`{ "title": "Patch taken","body": "Andela is the best office to work in",
+								"category": "Extras","publisher": "Femonofsky"}`,
+	}
+	for _, article := range articles {
+		res, err := http.Post(fmt.Sprintf("%s/article", server.URL), "application/json", strings.NewReader(article))
+		if err != nil || res.StatusCode != http.StatusCreated {
+			t.Fatalf("could not create article: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		params      string
+		contentType string
+		body        string
+		want        int
+		wantBody    map[string]interface{}
+	}{
+		{"case 01", `1`, mergePatchType, `{"body": "money in the bank"}`, http.StatusOK,
+			map[string]interface{}{"title": "Patch test", "body": "money in the bank", "published_at": "2020-02-25 19:02:35"}},
+		{"case 02", `1`, mergePatchType, `{"published_at": null, "category": "News"}`, http.StatusOK,
+			map[string]interface{}{"category": "News", "published_at": "0001-01-01 00:00:00"}},
+		{"case 03", `1`, "application/json", `{"id": 7}`, http.StatusOK,
+			map[string]interface{}{"id": float64(1)}},
+		{"case 04", `1`, mergePatchType, `{"title": null}`, http.StatusUnprocessableEntity, nil},
+		{"case 05", `1`, mergePatchType, `{"title": "Patch taken"}`, http.StatusConflict, nil},
+		{"case 06", `1`, mergePatchType, `{"title": 7}`, http.StatusBadRequest, nil},
+		{"case 07", `1`, "text/plain", `{"body": "money"}`, http.StatusUnsupportedMediaType, nil},
+		{"case 08", `990`, mergePatchType, `{"body": "money"}`, http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := http.Client{}
+			url := fmt.Sprintf("%s/article/%s", server.URL, tt.params)
+			req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("could not create PATCH request: %v", err)
+			}
+			req.Header.Set("Content-Type", tt.contentType)
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("could not send PATCH request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			if tt.wantBody == nil {
+				return
+			}
+			var body struct {
+				Data map[string]interface{} `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+			for field, want := range tt.wantBody {
+				if body.Data[field] != want {
+					t.Errorf("%v = %v, want %v", field, body.Data[field], want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewArticleController_ETag(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := `{ "title": "ETag test","body": "Andela is the best office to work in",
+								"category": "Extras","publisher": "Femonofsky"}`
+	res, err := http.Post(fmt.Sprintf("%s/article", server.URL), "application/json", strings.NewReader(article))
+	if err != nil || res.StatusCode != http.StatusCreated {
+		t.Fatalf("could not create article: %v", err)
+	}
+	created := res.Header.Get("ETag")
+
+	do := func(method, header, value, body string) *http.Response {
+		client := http.Client{}
+		req, err := http.NewRequest(method, fmt.Sprintf("%s/article/1", server.URL), strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("could not create request: %v", err)
+		}
+		if value != "" {
+			req.Header.Set(header, value)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("could not send request: %v", err)
+		}
+		res.Body.Close()
+		return res
+	}
+
+	res = do(http.MethodGet, "", "", "")
+	if res.StatusCode != http.StatusOK || res.Header.Get("ETag") != created || created == "" {
+		t.Fatalf("GET etag = %v, want %v", res.Header.Get("ETag"), created)
+	}
+	if res = do(http.MethodGet, "If-None-Match", created, ""); res.StatusCode != http.StatusNotModified {
+		t.Errorf("expected status not modified; got %v", res.Status)
+	}
+	if res = do(http.MethodGet, "If-None-Match", "W/"+created, ""); res.StatusCode != http.StatusNotModified {
+		t.Errorf("expected status not modified for weak etag; got %v", res.Status)
+	}
+
+	res = do(http.MethodPatch, "If-Match", created, `{"body": "first editor"}`)
+	if res.StatusCode != http.StatusOK || res.Header.Get("ETag") == created {
+		t.Fatalf("expected status ok with a new etag; got %v %v", res.Status, res.Header.Get("ETag"))
+	}
+	renamed := res.Header.Get("ETag")
+
+	// renaming the category changes the article and so its etag
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/category/1", server.URL), strings.NewReader(`{"name": "Specials"}`))
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+	if res, err = http.DefaultClient.Do(req); err != nil || res.StatusCode != http.StatusOK {
+		t.Fatalf("could not rename category: %v", err)
+	}
+	res.Body.Close()
+	res = do(http.MethodGet, "If-None-Match", renamed, "")
+	if res.StatusCode != http.StatusOK || res.Header.Get("ETag") == renamed {
+		t.Fatalf("expected status ok with a new etag after the rename; got %v %v", res.Status, res.Header.Get("ETag"))
+	}
+	updated := res.Header.Get("ETag")
+
+	tests := []struct {
+		name   string
+		method string
+		header string
+		value  string
+		body   string
+		want   int
+	}{
+		{"case 01", http.MethodGet, "If-None-Match", created, ``, http.StatusOK},
+		{"case 02", http.MethodPatch, "If-Match", created, `{"body": "second editor"}`, http.StatusPreconditionFailed},
+		{"case 03", http.MethodPut, "If-Match", created, `{"title": "ETag test","body": "second editor",
+								"category": "Extras","publisher": "Femonofsky"}`, http.StatusPreconditionFailed},
+		{"case 04", http.MethodDelete, "If-Match", created, ``, http.StatusPreconditionFailed},
+		{"case 05", http.MethodPut, "If-Match", renamed, `{"title": "ETag test","body": "second editor",
+								"category": "Extras","publisher": "Femonofsky"}`, http.StatusPreconditionFailed},
+		{"case 06", http.MethodPut, "If-Match", `"other", ` + updated, `{"title": "ETag test","body": "second editor",
+								"category": "Specials","publisher": "Femonofsky"}`, http.StatusOK},
+		{"case 07", http.MethodDelete, "If-Match", `*`, ``, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if res := do(tt.method, tt.header, tt.value, tt.body); res.StatusCode != tt.want {
+				t.Errorf("expected status %v; got %v", tt.want, res.Status)
+			}
+		})
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   string
+		fields []model.FieldError
+	}{
+		{"case 01", http.MethodPost, "/article", `{"title": "Error test", "body": "Andela"}`, "validation_failed",
+			[]model.FieldError{{Field: "category", Rule: "required"}, {Field: "publisher", Rule: "required"}}},
+		{"case 02", http.MethodPost, "/article", `{"title": "Error test", "published_at": "today"}`, "validation_failed",
+			[]model.FieldError{{Field: "published_at", Rule: "datetime", Param: model.DateTimeLayout}}},
+		{"case 03", http.MethodPost, "/category", `{}`, "validation_failed",
+			[]model.FieldError{{Field: "name", Rule: "required"}}},
+		{"case 04", http.MethodPost, "/publisher", `{"name": "Tunde", "website": "tunde"}`, "validation_failed",
+			[]model.FieldError{{Field: "website", Rule: "url"}}},
+		{"case 05", http.MethodGet, "/category/990", ``, "not_found", nil},
+		{"case 06", http.MethodGet, "/article/990", ``, "not_found", nil},
+		{"case 07", http.MethodPost, "/category", `{"name": "Error"}`, "", nil},
+		{"case 08", http.MethodPost, "/category", `{"name": "Error"}`, "conflict", nil},
+		{"case 09", http.MethodPost, "/article", `{"title": `, "bad_request", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := http.Client{}
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+
+			if tt.code == "" {
+				return
+			}
+			var body struct {
+				Success bool      `json:"success"`
+				Data    errorBody `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+			if body.Success || body.Data.Code != tt.code || body.Data.Message == "" {
+				t.Errorf("unexpected error body: %+v", body)
+			}
+			if len(body.Data.Fields) != len(tt.fields) {
+				t.Fatalf("fields = %v, want %v", body.Data.Fields, tt.fields)
+			}
+			for i, field := range tt.fields {
+				if body.Data.Fields[i] != field {
+					t.Errorf("field %d = %v, want %v", i, body.Data.Fields[i], field)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	secret := fmt.Errorf(`pq: password authentication failed for user "postgres"`)
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		want   int
+		code   string
+	}{
+		{"case 01", model.ErrArticleNotFound, http.StatusBadRequest, http.StatusNotFound, "not_found"},
+		{"case 02", model.ErrTitleExists, http.StatusBadRequest, http.StatusConflict, "conflict"},
+		{"case 03", &model.ValidationError{}, http.StatusBadRequest, http.StatusUnprocessableEntity, "validation_failed"},
+		{"case 04", &model.StorageError{Err: secret}, http.StatusBadRequest, http.StatusInternalServerError, "internal_server_error"},
+		{"case 05", model.ErrArticleChanged, http.StatusBadRequest, http.StatusPreconditionFailed, "precondition_failed"},
+		{"case 06", fmt.Errorf("invalid Id"), http.StatusBadRequest, http.StatusBadRequest, "bad_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := errorStatus(tt.err, tt.status)
+			if status != tt.want {
+				t.Errorf("errorStatus() = %v, want %v", status, tt.want)
+			}
+			body := newErrorBody(tt.err, status)
+			if body.Code != tt.code || strings.Contains(body.Message, "postgres") {
+				t.Errorf("newErrorBody() = %+v", body)
+			}
+		})
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := `{ "title": "Format test","body": "Andela is the best office to work in",
+								"category": "Extras","publisher": "Femonofsky"}`
+	res, err := http.Post(fmt.Sprintf("%s/article", server.URL), "application/json", strings.NewReader(article))
+	if err != nil || res.StatusCode != http.StatusCreated {
+		t.Fatalf("could not create article: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		want        int
+		contentType string
+		body        string
+	}{
+		{"case 01", "/article", "text/csv", http.StatusOK, "text/csv",
+			"id,title,body,category,publisher,created_at,published_at\n1,Format test,"},
+		{"case 02", "/article?format=xml", "", http.StatusOK, "application/xml", "<title>Format test</title>"},
+		{"case 03", "/article/1", "application/yaml", http.StatusOK, "application/yaml", "title: Format test"},
+		{"case 04", "/article/1", "text/csv", http.StatusNotAcceptable, "application/json", `"code":"not_acceptable"`},
+		{"case 05", "/article", "text/html", http.StatusNotAcceptable, "application/json", `"code":"not_acceptable"`},
+		{"case 06", "/article/990?format=xml", "", http.StatusNotFound, "application/xml", "<code>not_found</code>"},
+		{"case 07", "/article/990?format=csv", "", http.StatusNotAcceptable, "application/json", `"code":"not_acceptable"`},
+		{"case 08", "/article/1", "text/csv, application/yaml;q=0.5", http.StatusOK, "application/yaml", "title: Format test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			body, _ := ioutil.ReadAll(res.Body)
+			if res.StatusCode != tt.want {
+				t.Errorf("expected status %v; got %v", tt.want, res.Status)
+			}
+			if res.Header.Get("Content-Type") != tt.contentType {
+				t.Errorf("Content-Type = %v, want %v", res.Header.Get("Content-Type"), tt.contentType)
+			}
+			if !strings.Contains(string(body), tt.body) {
+				t.Errorf("body = %s, want it to contain %s", body, tt.body)
+			}
+		})
+	}
+
+	// the format is refused before the article is created
+	article = `{"title": "Csv test", "body": "Andela", "category": "Extras", "publisher": "Femonofsky"}`
+	res, err = http.Post(server.URL+"/v1/article?format=csv", "application/json", strings.NewReader(article))
+	if err != nil {
+		t.Fatalf("could not send POST request: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("expected status %v; got %v", http.StatusNotAcceptable, res.Status)
+	}
+	if _, total, err := testStore.GetArticlesPage(model.ArticleFilter{}, model.Page{}); err != nil || total != 1 {
+		t.Errorf("%d articles stored (%v), want 1", total, err)
+	}
+}
+
+func TestNewArticleController_Bulk(t *testing.T) {
+	batch := []string{
+		`{"title": "Bulk one", "body": "Andela", "category": "Extras", "publisher": "Femonofsky"}`,
+		`{"title": "Bulk two", "body": "Andela", "category": "Extras", "publisher": "Femonofsky"}`,
+		`{"title": "Bulk one", "body": "Andela", "category": "Extras", "publisher": "Femonofsky"}`,
+		`{"title": "Bulk three", "body": "Andela"}`,
+		`{"title": `,
+	}
+	array := func(items ...string) string { return "[" + strings.Join(items, ",") + "]" }
+	ndjson := func(items ...string) string { return strings.Join(items, "\n") + "\n" }
+	// huge is one article over the byte limit of a bulk import
+	huge := `{"title": "Huge", "body": "` + strings.Repeat("a", maxBulkBytes) + `", "category": "Extras", "publisher": "Femonofsky"}`
+
+	tests := []struct {
+		name        string
+		params      string
+		contentType string
+		body        string
+		want        int
+		codes       []string
+		stored      int
+	}{
+		{"case 01", ``, "application/json", array(batch[:2]...), http.StatusCreated, []string{"", ""}, 2},
+		{"case 02", ``, "application/json", array(batch[:4]...), http.StatusUnprocessableEntity,
+			[]string{"failed_dependency", "failed_dependency", "conflict", "validation_failed"}, 0},
+		{"case 03", `?mode=best_effort`, "application/json", array(batch[:4]...), http.StatusMultiStatus,
+			[]string{"", "", "conflict", "validation_failed"}, 2},
+		{"case 04", `?mode=best_effort`, ndjsonType, ndjson(batch...), http.StatusMultiStatus,
+			[]string{"", "", "conflict", "validation_failed", "bad_request"}, 2},
+		{"case 05", ``, ndjsonType, ndjson(batch[0], "", batch[4]), http.StatusUnprocessableEntity,
+			[]string{"failed_dependency", "bad_request"}, 0},
+		{"case 06", ``, "application/json", batch[0], http.StatusBadRequest, nil, 0},
+		{"case 07", ``, "application/json", `[]`, http.StatusBadRequest, nil, 0},
+		{"case 08", `?mode=some`, "application/json", array(batch[:2]...), http.StatusBadRequest, nil, 0},
+		{"case 09", ``, "text/plain", array(batch[:2]...), http.StatusUnsupportedMediaType, nil, 0},
+		{"case 10", ``, ndjsonType, ndjson(batch[0], huge), http.StatusRequestEntityTooLarge, nil, 0},
+		{"case 11", ``, "application/json", array(batch[0], huge), http.StatusRequestEntityTooLarge, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := refreshAllTable(testDB); err != nil {
+				t.Fatal("unable to refreshTable")
+			}
+			url := fmt.Sprintf("%s/article/bulk%s", server.URL, tt.params)
+			res, err := http.Post(url, tt.contentType, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("could not send POST request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+
+			var body struct {
+				Data struct {
+					Items []struct {
+						Index   int            `json:"index"`
+						Success bool           `json:"success"`
+						Article *model.Article `json:"article"`
+						Error   *errorBody     `json:"error"`
+					} `json:"items"`
+				} `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+			if len(body.Data.Items) != len(tt.codes) {
+				t.Fatalf("got %d items, want %d", len(body.Data.Items), len(tt.codes))
+			}
+			for i, item := range body.Data.Items {
+				if item.Index != i || item.Success != (tt.codes[i] == "") {
+					t.Errorf("unexpected item %d: %+v", i, item)
+				}
+				if item.Error != nil && item.Error.Code != tt.codes[i] {
+					t.Errorf("item %d error code = %v, want %v", i, item.Error.Code, tt.codes[i])
+				}
+			}
+
+			articles, _, err := testStore.GetArticlesPage(model.ArticleFilter{}, model.Page{})
+			if err != nil {
+				t.Fatalf("unable to get articles %v", err)
+			}
+			if len(articles) != tt.stored {
+				t.Errorf("stored %d articles, want %d", len(articles), tt.stored)
+			}
+		})
+	}
+}
+
+func TestNewArticleController_Export(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	for _, title := range []string{"Export one", "Export two", "Other"} {
+		article := fmt.Sprintf(`{"title": "%s", "body": "Andela", "category": "Extras", "publisher": "Femonofsky"}`, title)
+		res, err := http.Post(fmt.Sprintf("%s/article", server.URL), "application/json", strings.NewReader(article))
+		if err != nil || res.StatusCode != http.StatusCreated {
+			t.Fatalf("could not create article: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		params string
+		want   int
+		titles []string
+	}{
+		{"case 01", ``, http.StatusOK, []string{"Export one", "Export two", "Other"}},
+		{"case 02", `?q=export`, http.StatusOK, []string{"Export one", "Export two"}},
+		{"case 03", `?publisher=Nobody`, http.StatusOK, nil},
+		{"case 04", `?created_from=today`, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s/article/export%s", server.URL, tt.params))
+			if err != nil {
+				t.Fatalf("could not send GET request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			if contentType := res.Header.Get("Content-Type"); contentType != ndjsonType {
+				t.Errorf("Content-Type = %v, want %v", contentType, ndjsonType)
+			}
+
+			var titles []string
+			dec := json.NewDecoder(res.Body)
+			for dec.More() {
+				var article map[string]interface{}
+				if err := dec.Decode(&article); err != nil {
+					t.Fatalf("could not decode line: %v", err)
+				}
+				titles = append(titles, article["title"].(string))
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.titles, ",") {
+				t.Errorf("exported %v, want %v", titles, tt.titles)
+			}
+		})
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	if err := refreshAllTable(testDB); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		want       int
+		deprecated bool
+	}{
+		{"case 01", "/v1/article", http.StatusOK, false},
+		{"case 02", "/v1/category/", http.StatusOK, false},
+		{"case 03", "/article", http.StatusOK, true},
+		{"case 04", "/category/", http.StatusOK, true},
+		{"case 05", "/v1/openapi.json", http.StatusOK, false},
+		{"case 06", "/v2/article", http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("could not send GET request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			deprecation, sunset := res.Header.Get("Deprecation"), res.Header.Get("Sunset")
+			if !tt.deprecated {
+				if deprecation != "" || sunset != "" {
+					t.Errorf("unexpected deprecation headers %q %q", deprecation, sunset)
+				}
+				return
+			}
+			if !strings.HasPrefix(deprecation, "@") || sunset == "" {
+				t.Errorf("missing deprecation headers %q %q", deprecation, sunset)
+			}
+			if link := res.Header.Get("Link"); link != fmt.Sprintf(`</v1%s>; rel="successor-version"`, tt.path) {
+				t.Errorf("Link = %v", link)
+			}
+		})
+	}
+}
+
+// stubStore serves fixed articles and fails to store any, the other records come from the embedded store
+type stubStore struct {
+	model.Store
+}
+
+func (stubStore) GetArticle(id int) (*model.Article, error) {
+	if id != 1 {
+		return nil, model.ErrArticleNotFound
+	}
+	return &model.Article{ID: 1, Title: "Stubbed", Body: "Not in the database", CategoryName: "stubs",
+		PublisherName: "stub", Version: 1}, nil
+}
+
+func (s stubStore) WithContext(ctx context.Context) model.Store {
+	return s
+}
+
+func (stubStore) CreateArticle(article *model.Article) error {
+	return &model.StorageError{Err: fmt.Errorf("disk full")}
+}
+
+func TestStore(t *testing.T) {
+	srv := httptest.NewServer(withAPIKey(New(log.New(ioutil.Discard, "", 0), &testConfig, stubStore{testStore}, nil)))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+		title  string
+	}{
+		{"case 01", http.MethodGet, "/v1/article/1", ``, http.StatusOK, "Stubbed"},
+		{"case 02", http.MethodGet, "/v1/article/2", ``, http.StatusNotFound, ""},
+		{"case 03", http.MethodPost, "/v1/article", `{"title": "Full", "body": "Disk", "category": "stubs", "publisher": "stub"}`,
+			http.StatusInternalServerError, ""},
+		{"case 04", http.MethodGet, "/v1/category", ``, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			if tt.title == "" {
+				return
+			}
+			var body struct {
+				Data model.Article `json:"data"`
+			}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode body: %v", err)
+			}
+			if body.Data.Title != tt.title {
+				t.Errorf("title = %v, want %v", body.Data.Title, tt.title)
+			}
+		})
+	}
+}
+
+// The memory store needs no database, each test server gets its own so they run in parallel
+func TestMemoryDriver(t *testing.T) {
+	steps := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodPost, "/v1/article", `{"title": "Memory", "body": "Kept in memory", "category": "Extras", "publisher": "Femonofsky"}`, http.StatusCreated},
+		{http.MethodPost, "/v1/article", `{"title": "Memory", "body": "Again", "category": "Extras", "publisher": "Femonofsky"}`, http.StatusConflict},
+		{http.MethodGet, "/v1/article/1", ``, http.StatusOK},
+		{http.MethodPut, "/v1/category/1", `{"name": "Specials"}`, http.StatusOK},
+		{http.MethodGet, "/v1/article?category=Specials&q=memory", ``, http.StatusOK},
+		{http.MethodDelete, "/v1/category/1", ``, http.StatusConflict},
+		{http.MethodDelete, "/v1/article/1", ``, http.StatusNoContent},
+		{http.MethodGet, "/v1/article/1", ``, http.StatusNotFound},
+		{http.MethodDelete, "/v1/category/1", ``, http.StatusNoContent},
+		{http.MethodGet, "/readyz", ``, http.StatusOK},
+	}
+
+	for _, name := range []string{"case 01", "case 02", "case 03", "case 04"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			srv := httptest.NewServer(withAPIKey(New(nil, &testConfig, model.NewMemoryStore(), nil)))
+			defer srv.Close()
+			for i, step := range steps {
+				req, err := http.NewRequest(step.method, srv.URL+step.path, strings.NewReader(step.body))
+				if err != nil {
+					t.Fatalf("could not create request: %v", err)
+				}
+				res, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatalf("could not send request: %v", err)
+				}
+				res.Body.Close()
+				if res.StatusCode != step.want {
+					t.Fatalf("step %d %s %s: expected status %v; got %v", i+1, step.method, step.path, step.want, res.Status)
+				}
+			}
+		})
+	}
+}
+
+// slowStore takes delay to list and create articles and to read each exported one
+type slowStore struct {
+	model.Store
+	delay time.Duration
+}
+
+func (s slowStore) WithContext(ctx context.Context) model.Store {
+	return slowStore{s.Store.WithContext(ctx), s.delay}
+}
+
+func (s slowStore) CreateArticle(article *model.Article) error {
+	time.Sleep(s.delay)
+	return s.Store.CreateArticle(article)
+}
+
+func (s slowStore) GetArticlesPage(filter model.ArticleFilter, page model.Page) (model.Articles, int, error) {
+	time.Sleep(s.delay)
+	return s.Store.GetArticlesPage(filter, page)
+}
+
+func (s slowStore) EachArticle(filter model.ArticleFilter, fn func(*model.Article) error) error {
+	return s.Store.EachArticle(filter, func(article *model.Article) error {
+		time.Sleep(s.delay)
+		return fn(article)
+	})
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	store := model.NewMemoryStore()
+	for _, title := range []string{"Slow one", "Slow two", "Slow three"} {
+		if err := store.CreateArticle(&model.Article{Title: title, Body: "Andela", CategoryName: "Extras", PublisherName: "Femonofsky"}); err != nil {
+			t.Fatalf("could not create article: %v", err)
+		}
+	}
+	cfg := testConfig
+	cfg.Server.HandlerTimeout = config.Duration(200 * time.Millisecond)
+	srv := httptest.NewServer(New(nil, &cfg, slowStore{store, 100 * time.Millisecond}, nil))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		path  string
+		want  int
+		lines int
+	}{
+		{"case 01", "/v1/category", http.StatusOK, 0},
+		{"case 02", "/v1/article", http.StatusOK, 0},
+		// the export streams for longer than the timeout of the other routes
+		{"case 03", "/v1/article/export", http.StatusOK, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatalf("could not read body: %v", err)
+			}
+			if tt.lines > 0 && strings.Count(string(body), "\n") != tt.lines {
+				t.Errorf("got body %q, want %d articles", body, tt.lines)
+			}
+		})
+	}
+
+	// a listing or a write slower than the timeout answers 503 in the envelope, the write is not stored
+	slow := httptest.NewServer(New(nil, &cfg, slowStore{store, 500 * time.Millisecond}, nil))
+	defer slow.Close()
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		article := `{"title": "Too slow", "body": "Andela", "category": "Extras", "publisher": "Femonofsky"}`
+		req, err := http.NewRequest(method, slow.URL+"/v1/article", strings.NewReader(article))
+		if err != nil {
+			t.Fatalf("could not create request: %v", err)
+		}
+		req.Header.Set("X-API-Key", testAPIKey)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("could not send request: %v", err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusServiceUnavailable || !strings.Contains(string(body), `"code":"service_unavailable"`) {
+			t.Errorf("%v got %v %s, want %v with code service_unavailable", method, res.Status, body, http.StatusServiceUnavailable)
+		}
+	}
+	// the handler returned with the response, the write cannot complete later
+	if articles, _, _ := store.GetArticlesPage(model.ArticleFilter{Search: "slow"}, model.Page{}); len(articles) != 3 {
+		t.Errorf("%d articles stored, want the 3 created before", len(articles))
+	}
+}
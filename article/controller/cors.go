@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"github.com/femonofsky/articleMaker/article/config"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// corsMethods are the methods the API serves, probed to find the methods of a route
+	corsMethods = []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete}
+	// corsRequestHeaders are the request headers the API reads
+	corsRequestHeaders = []string{"Accept", "Authorization", "Content-Type", "If-Match", "If-None-Match", "X-API-Key", requestIDHeader}
+	// corsResponseHeaders are the response headers the API sets beside the ones browsers always expose
+	corsResponseHeaders = []string{"Deprecation", "ETag", "Link", "Retry-After", "Sunset", "WWW-Authenticate",
+		"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", requestIDHeader}
+)
+
+// corsPolicy applies the CORS configuration to the responses and answers preflight requests
+type corsPolicy struct {
+	cfg     config.CORS
+	methods []string
+	headers []string
+	exposed string
+}
+
+// newCORS fills the defaults of the unset lists of cfg
+func newCORS(cfg config.CORS) *corsPolicy {
+	c := &corsPolicy{cfg: cfg, methods: cfg.AllowedMethods, headers: cfg.AllowedHeaders}
+	if len(c.methods) == 0 {
+		c.methods = corsMethods
+	}
+	if len(c.headers) == 0 {
+		c.headers = corsRequestHeaders
+	}
+	exposed := cfg.ExposedHeaders
+	if len(exposed) == 0 {
+		exposed = corsResponseHeaders
+	}
+	c.exposed = strings.Join(exposed, ", ")
+	return c
+}
+
+// allowOrigin sets the origin headers of a response, it reports whether the origin of req may read it
+func (c *corsPolicy) allowOrigin(wr http.ResponseWriter, req *http.Request) bool {
+	if len(c.cfg.AllowedOrigins) == 0 {
+		return false
+	}
+	// the answer depends on the origin, caches must not serve it to another one
+	wr.Header().Add("Vary", "Origin")
+	origin := req.Header.Get("Origin")
+	if origin == "" || !contains(c.cfg.AllowedOrigins, origin) && !contains(c.cfg.AllowedOrigins, "*") {
+		return false
+	}
+	if contains(c.cfg.AllowedOrigins, "*") && !c.cfg.AllowCredentials {
+		wr.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		// browsers refuse a wildcard on requests with credentials
+		wr.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.cfg.AllowCredentials {
+		wr.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// middleware sets the CORS headers of the responses to allowed origins
+func (c *corsPolicy) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != "" {
+			// the preflight handler sets its own headers
+			next.ServeHTTP(wr, req)
+			return
+		}
+		if c.allowOrigin(wr, req) {
+			wr.Header().Set("Access-Control-Expose-Headers", c.exposed)
+		}
+		next.ServeHTTP(wr, req)
+	})
+}
+
+// preflight answers the OPTIONS requests of the routes of router with the methods they serve,
+// and allowed origins with the methods and headers they may use
+func (c *corsPolicy) preflight(router *mux.Router) http.HandlerFunc {
+	return func(wr http.ResponseWriter, req *http.Request) {
+		methods := routeMethods(router, req)
+		wr.Header().Set("Allow", strings.Join(append(methods, http.MethodOptions), ", "))
+
+		method := req.Header.Get("Access-Control-Request-Method")
+		if method != "" && c.allowOrigin(wr, req) {
+			var allowed []string
+			for _, m := range methods {
+				if contains(c.methods, m) {
+					allowed = append(allowed, m)
+				}
+			}
+			if contains(allowed, method) {
+				wr.Header().Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+				headers := strings.Join(c.headers, ", ")
+				if contains(c.headers, "*") {
+					// a wildcard is taken literally on requests with credentials, echo what the browser asks for
+					headers = req.Header.Get("Access-Control-Request-Headers")
+				}
+				if headers != "" {
+					wr.Header().Set("Access-Control-Allow-Headers", headers)
+				}
+				if c.cfg.MaxAge > 0 {
+					wr.Header().Set("Access-Control-Max-Age", strconv.Itoa(c.cfg.MaxAge))
+				}
+			}
+		}
+		wr.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// isPreflight matches the OPTIONS requests of the paths router serves
+func isPreflight(router *mux.Router) mux.MatcherFunc {
+	return func(req *http.Request, _ *mux.RouteMatch) bool {
+		// routeMethods matches the request again with other methods, which must not match here
+		return req.Method == http.MethodOptions && len(routeMethods(router, req)) > 0
+	}
+}
+
+// routeMethods returns the methods router serves on the path of req
+func routeMethods(router *mux.Router, req *http.Request) []string {
+	var methods []string
+	for _, method := range corsMethods {
+		probe := req.Clone(req.Context())
+		probe.Method = method
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// contains reports whether list holds s, ignoring case
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"github.com/femonofsky/articleMaker/article/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	cfg := testConfig
+	cfg.CORS = config.CORS{
+		AllowedOrigins:   []string{"https://app.example"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+	srv := httptest.NewServer(New(nil, &cfg, testStore, nil))
+	defer srv.Close()
+
+	cfg.CORS = config.CORS{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}}
+	wildcard := httptest.NewServer(New(nil, &cfg, testStore, nil))
+	defer wildcard.Close()
+
+	const app = "https://app.example"
+	tests := []struct {
+		name          string
+		srv           *httptest.Server
+		method        string
+		path          string
+		origin        string
+		requestMethod string
+		want          int
+		// headers are the expected response headers, an empty value must be absent
+		headers map[string]string
+	}{
+		{"case 01", srv, http.MethodOptions, "/v1/article/1", app, http.MethodPut, http.StatusNoContent, map[string]string{
+			"Allow":                            "GET, PUT, PATCH, DELETE, OPTIONS",
+			"Access-Control-Allow-Origin":      app,
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Allow-Methods":     "GET, PUT",
+			"Access-Control-Allow-Headers":     "Accept, Authorization, Content-Type, If-Match, If-None-Match, X-API-Key, X-Request-ID",
+			"Access-Control-Max-Age":           "600",
+		}},
+		{"case 02", srv, http.MethodOptions, "/article/", app, http.MethodPost, http.StatusNoContent, map[string]string{
+			"Allow":                        "GET, POST, OPTIONS",
+			"Access-Control-Allow-Origin":  app,
+			"Access-Control-Allow-Methods": "GET, POST",
+			"Deprecation":                  "",
+		}},
+		{"case 03", srv, http.MethodOptions, "/v1/article/1", app, http.MethodDelete, http.StatusNoContent, map[string]string{
+			"Access-Control-Allow-Origin":  app,
+			"Access-Control-Allow-Methods": "",
+		}},
+		{"case 04", srv, http.MethodOptions, "/v1/article/1", "https://evil.example", http.MethodPut, http.StatusNoContent, map[string]string{
+			"Allow":                        "GET, PUT, PATCH, DELETE, OPTIONS",
+			"Access-Control-Allow-Origin":  "",
+			"Access-Control-Allow-Methods": "",
+		}},
+		{"case 05", srv, http.MethodOptions, "/v1/nowhere", app, http.MethodGet, http.StatusNotFound, map[string]string{
+			"Access-Control-Allow-Methods": "",
+		}},
+		{"case 06", srv, http.MethodGet, "/v1/category", app, "", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin":   app,
+			"Access-Control-Expose-Headers": "Deprecation, ETag, Link, Retry-After, Sunset, WWW-Authenticate, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, X-Request-ID",
+			"Vary":                          "Origin",
+		}},
+		{"case 07", srv, http.MethodGet, "/v1/category", "", "", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		{"case 08", srv, http.MethodPost, "/v1/category", app, "", http.StatusUnauthorized, map[string]string{
+			"Access-Control-Allow-Origin": app,
+		}},
+		{"case 09", wildcard, http.MethodOptions, "/v1/category", app, http.MethodPost, http.StatusNoContent, map[string]string{
+			"Access-Control-Allow-Origin":      "*",
+			"Access-Control-Allow-Credentials": "",
+			"Access-Control-Allow-Headers":     "Content-Type, X-Api-Key",
+			"Access-Control-Max-Age":           "",
+		}},
+		{"case 10", server, http.MethodGet, "/v1/category", app, "", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		{"case 11", srv, http.MethodPost, "/v1/category/1", app, "", http.StatusMethodNotAllowed, map[string]string{
+			"Access-Control-Allow-Origin": app,
+			"Vary":                        "Origin",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+				req.Header.Set("Access-Control-Request-Headers", "Content-Type, X-Api-Key")
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			for name, want := range tt.headers {
+				if got := res.Header.Get(name); got != want {
+					t.Errorf("%v = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
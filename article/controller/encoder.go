@@ -0,0 +1,347 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// encoder writes the response envelope in one media type
+type encoder interface {
+	// mediaType is sent as Content-Type
+	mediaType() string
+	// encode writes res, it returns errNotRepresentable when the data does not fit the format
+	encode(w io.Writer, res response) error
+}
+
+// errNotRepresentable is returned by encoders that cannot write the data of a response, e.g csv of a single article
+var errNotRepresentable = fmt.Errorf("the response cannot be written in the requested format")
+
+// encoders by the value of the format query parameter, add an entry to support a new format
+var encoders = map[string]encoder{
+	"json": jsonEncoder{},
+	"xml":  xmlEncoder{},
+	"csv":  csvEncoder{},
+	"yaml": yamlEncoder{},
+}
+
+// mediaTypes maps the media types of the Accept header to the format of their encoder
+var mediaTypes = map[string]string{
+	"application/json":   "json",
+	"application/xml":    "xml",
+	"text/xml":           "xml",
+	"text/csv":           "csv",
+	"application/yaml":   "yaml",
+	"application/x-yaml": "yaml",
+	"text/yaml":          "yaml",
+}
+
+// negotiate picks the encoder from the format query parameter, or else from the Accept header.
+// csv only writes lists, it is skipped unless list is set.
+// It returns false when none of the acceptable formats is supported
+func negotiate(r *http.Request, list bool) (encoder, bool) {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		enc, ok := encoders[format]
+		return enc, ok && (list || format != "csv")
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return encoders["json"], true
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, mr := range ranges {
+		if mr.mediaType == "*/*" {
+			return encoders["json"], true
+		}
+		if strings.HasSuffix(mr.mediaType, "/*") {
+			// prefer json, then the formats in a fixed order, for a wildcard subtype
+			for _, format := range []string{"json", "xml", "yaml", "csv"} {
+				if format == "csv" && !list {
+					continue
+				}
+				if strings.HasPrefix(encoders[format].mediaType(), strings.TrimSuffix(mr.mediaType, "*")) {
+					return encoders[format], true
+				}
+			}
+			continue
+		}
+		if format, ok := mediaTypes[mr.mediaType]; ok && (list || format != "csv") {
+			return encoders[format], true
+		}
+	}
+	return nil, false
+}
+
+// jsonEncoder writes the envelope as JSON
+type jsonEncoder struct{}
+
+func (jsonEncoder) mediaType() string { return "application/json" }
+
+func (jsonEncoder) encode(w io.Writer, res response) error {
+	return json.NewEncoder(w).Encode(res)
+}
+
+// xmlEncoder writes the envelope as XML, json field names become elements and list entries <item> elements
+type xmlEncoder struct{}
+
+func (xmlEncoder) mediaType() string { return "application/xml" }
+
+func (xmlEncoder) encode(w io.Writer, res response) error {
+	tree, err := toTree(res)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := writeXML(enc, "response", tree); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// writeXML writes a value decoded by toTree as the element name
+func writeXML(enc *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case object:
+		for _, m := range v {
+			if err := writeXML(enc, m.key, m.value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := writeXML(enc, "item", item); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := enc.EncodeToken(xml.CharData(scalar(v))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// yamlEncoder writes the envelope as YAML keeping the order of the json fields
+type yamlEncoder struct{}
+
+func (yamlEncoder) mediaType() string { return "application/yaml" }
+
+func (yamlEncoder) encode(w io.Writer, res response) error {
+	tree, err := toTree(res)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(toYAML(tree))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// toYAML converts a value decoded by toTree into values yaml.v2 writes in order
+func toYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case object:
+		out := make(yaml.MapSlice, 0, len(v))
+		for _, m := range v {
+			out = append(out, yaml.MapItem{Key: m.key, Value: toYAML(m.value)})
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			out = append(out, toYAML(item))
+		}
+		return out
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
+
+// csvEncoder writes the data of list responses as CSV, one row per entry with the json fields as header
+type csvEncoder struct{}
+
+func (csvEncoder) mediaType() string { return "text/csv" }
+
+func (csvEncoder) encode(w io.Writer, res response) error {
+	tree, err := toTree(res.Data)
+	if err != nil {
+		return err
+	}
+	rows, ok := tree.([]interface{})
+	if !ok {
+		return errNotRepresentable
+	}
+
+	var header []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		fields, ok := row.(object)
+		if !ok {
+			return errNotRepresentable
+		}
+		for _, m := range fields {
+			if !seen[m.key] {
+				seen[m.key] = true
+				header = append(header, m.key)
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for _, m := range row.(object) {
+			for i, column := range header {
+				if column == m.key {
+					record[i] = scalar(m.value)
+				}
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// object is a decoded json object keeping the order of its fields
+type object []member
+
+// member is one field of an object
+type member struct {
+	key   string
+	value interface{}
+}
+
+// toTree encodes value as json and decodes it again into objects, []interface{}, json.Number, string, bool and nil.
+// Going through json keeps the custom MarshalJSON of the models and their field order
+func toTree(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeTree(dec)
+}
+
+// decodeTree reads the next json value from dec
+func decodeTree(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		obj := object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key: key.(string), value: value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token()
+		return list, err
+	}
+	return token, nil
+}
+
+// scalar formats a value decoded by toTree as text, nested values are written as json
+func scalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, _ := json.Marshal(toJSON(value))
+	return string(data)
+}
+
+// toJSON converts objects back into values encoding/json writes
+func toJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case object:
+		out := make(map[string]interface{}, len(v))
+		for _, m := range v {
+			out[m.key] = toJSON(m.value)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			out = append(out, toJSON(item))
+		}
+		return out
+	}
+	return value
+}
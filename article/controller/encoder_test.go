@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		list   bool
+		want   string
+		ok     bool
+	}{
+		{"case 01", "/article", "", true, "application/json", true},
+		{"case 02", "/article", "application/xml", true, "application/xml", true},
+		{"case 03", "/article", "text/csv;q=0.5, application/x-yaml", true, "application/yaml", true},
+		{"case 04", "/article", "text/html, */*;q=0.1", true, "application/json", true},
+		{"case 05", "/article", "text/*", true, "text/csv", true},
+		{"case 06", "/article", "text/html", true, "", false},
+		{"case 07", "/article", "application/json;q=0", true, "", false},
+		{"case 08", "/article?format=CSV", "application/json", true, "text/csv", true},
+		{"case 09", "/article?format=html", "", true, "", false},
+		{"case 10", "/article/1", "text/csv", false, "", false},
+		{"case 11", "/article/1", "text/csv, application/json;q=0.5", false, "application/json", true},
+		{"case 12", "/article/1?format=csv", "", false, "", false},
+		{"case 13", "/article/1", "text/*", false, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			enc, ok := negotiate(req, tt.list)
+			if ok != tt.ok {
+				t.Fatalf("negotiate() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && enc.mediaType() != tt.want {
+				t.Errorf("negotiate() = %v, want %v", enc.mediaType(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	list := response{Success: true, Data: []map[string]interface{}{
+		{"id": 1, "title": "Money, money", "tags": []string{"a"}},
+		{"id": 2, "title": "Love", "extra": nil},
+	}, Pagination: &pagination{Total: 2, Limit: 20}}
+	single := response{Success: true, Data: map[string]interface{}{"id": 1, "title": "Money"}}
+
+	tests := []struct {
+		name    string
+		enc     encoder
+		res     response
+		want    []string
+		wantErr error
+	}{
+		{"case 01", xmlEncoder{}, list, []string{`<?xml`, `<response><success>true</success><data><item>`,
+			`<title>Money, money</title>`, `<tags><item>a</item></tags>`, `<total>2</total>`}, nil},
+		{"case 02", yamlEncoder{}, list, []string{"success: true\n", "- extra: null\n", "  title: Love\n", "  total: 2\n"}, nil},
+		{"case 03", csvEncoder{}, list, []string{"id,tags,title,extra\n", `1,"[""a""]","Money, money",` + "\n", "2,,Love,\n"}, nil},
+		{"case 04", csvEncoder{}, single, nil, errNotRepresentable},
+		{"case 05", jsonEncoder{}, single, []string{`{"success":true,"data":{"id":1,"title":"Money"}}`}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tt.enc.encode(&buf, tt.res)
+			if err != tt.wantErr {
+				t.Fatalf("encode() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("encode() = %v, want it to contain %v", buf.String(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/model"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// the lifecycle states of the server reported by /readyz
+const (
+	stateStarting int32 = iota
+	stateReady
+	stateDraining
+)
+
+// stateNames are the statuses of /readyz by state
+var stateNames = map[int32]string{stateStarting: "starting", stateReady: "ready", stateDraining: "draining"}
+
+// Health is the lifecycle of the server, main moves it from starting to ready and to draining on shutdown.
+// It is safe for concurrent use
+type Health struct {
+	state  int32
+	checks []healthCheck
+}
+
+// healthCheck is a dependency the server needs to serve requests.
+// failure is what /readyz shows when the check fails, the cause is only logged as it may name internal hosts
+type healthCheck struct {
+	name    string
+	failure string
+	check   func() error
+}
+
+// NewHealth creates a Health that is starting and checks the storage of store and its migrations
+func NewHealth(store model.Store) *Health {
+	return &Health{checks: []healthCheck{
+		{"database", "unreachable", store.Ping},
+		{"migrations", "not migrated", store.Migrated},
+	}}
+}
+
+// Ready reports the server ready once it can serve requests
+func (h *Health) Ready() {
+	atomic.StoreInt32(&h.state, stateReady)
+}
+
+// Drain reports the server not ready while it stops, so no new requests are sent to it
+func (h *Health) Drain() {
+	atomic.StoreInt32(&h.state, stateDraining)
+}
+
+// checkResult is the outcome of a dependency check
+type checkResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// readiness is the body of /readyz
+type readiness struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks"`
+}
+
+// live answers /healthz as long as the process can serve a request
+func (h *Health) live(wr http.ResponseWriter, req *http.Request) {
+	writeHealth(wr, req, http.StatusOK, map[string]string{"status": "alive"})
+}
+
+// ready answers /readyz with the state of the server and the checks of its dependencies,
+// 503 unless the server is ready and every check passes
+func (h *Health) ready(wr http.ResponseWriter, req *http.Request) {
+	state := atomic.LoadInt32(&h.state)
+	res := readiness{Status: stateNames[state], Checks: make([]checkResult, len(h.checks))}
+	status := http.StatusOK
+	if state != stateReady {
+		status = http.StatusServiceUnavailable
+	}
+	for i, c := range h.checks {
+		start := time.Now()
+		err := c.check()
+		res.Checks[i] = checkResult{Name: c.name, Status: "ok", LatencyMS: float64(time.Since(start)) / float64(time.Millisecond)}
+		if err != nil {
+			logFailure(req, fmt.Errorf("%s check: %v", c.name, err))
+			res.Checks[i].Status, res.Checks[i].Error = "failed", c.failure
+			if state == stateReady {
+				res.Status = "unavailable"
+			}
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeHealth(wr, req, status, res)
+}
+
+// writeHealth writes the json body of a health endpoint as is, without the response envelope, never cached
+func writeHealth(wr http.ResponseWriter, req *http.Request, status int, body interface{}) {
+	wr.Header().Set("Content-Type", "application/json")
+	wr.Header().Set("Cache-Control", "no-store")
+	wr.WriteHeader(status)
+	if err := json.NewEncoder(wr).Encode(body); err != nil {
+		logFailure(req, err)
+	}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	failing := false
+	health := &Health{checks: []healthCheck{
+		{"database", "unreachable", func() error { return nil }},
+		{"migrations", "not migrated", func() error {
+			if failing {
+				return fmt.Errorf("tables not migrated: articles")
+			}
+			return nil
+		}},
+	}}
+	var out syncBuffer
+	srv := httptest.NewServer(New(log.New(&out, "", 0), &testConfig, testStore, health))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		srv     *httptest.Server
+		path    string
+		prepare func()
+		want    int
+		status  string
+		checks  []string
+	}{
+		{"case 01", srv, "/healthz", func() {}, http.StatusOK, "alive", nil},
+		{"case 02", srv, "/readyz", func() {}, http.StatusServiceUnavailable, "starting", []string{"ok", "ok"}},
+		{"case 03", srv, "/readyz", health.Ready, http.StatusOK, "ready", []string{"ok", "ok"}},
+		{"case 04", srv, "/readyz", func() { failing = true }, http.StatusServiceUnavailable, "unavailable", []string{"ok", "failed"}},
+		{"case 05", srv, "/readyz", health.Drain, http.StatusServiceUnavailable, "draining", []string{"ok", "failed"}},
+		{"case 06", srv, "/healthz", func() {}, http.StatusOK, "alive", nil},
+		{"case 07", server, "/readyz", func() {}, http.StatusOK, "ready", []string{"ok", "ok"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepare()
+			res, err := http.Get(tt.srv.URL + tt.path)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+
+			var body readiness
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode body: %v", err)
+			}
+			if body.Status != tt.status || len(body.Checks) != len(tt.checks) {
+				t.Fatalf("body = %+v, want status %v and %d checks", body, tt.status, len(tt.checks))
+			}
+			for i, c := range body.Checks {
+				if c.Status != tt.checks[i] || (c.Error != "") != (c.Status == "failed") || c.LatencyMS < 0 {
+					t.Errorf("check %v = %+v, want status %v", c.Name, c, tt.checks[i])
+				}
+				// the cause of a failure is logged, clients only see what failed
+				if c.Status == "failed" && (c.Error != "not migrated" || !strings.Contains(out.String(), "tables not migrated: articles")) {
+					t.Errorf("check %v = %+v, want error not migrated and the cause logged", c.Name, c)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+)
+
+// requestIDHeader carries the id of a request, propagated from the client or generated
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID limits the propagated ids to what is safe to write in logs and headers
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// fallbackLogger is used outside of the access log middleware, e.g when a handler is called directly
+var fallbackLogger = log.New(os.Stderr, "", log.LstdFlags)
+
+// newRequestID generates a random request id
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestIDFromContext returns the id the access log middleware gave the request of ctx
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
+
+// LoggerFromContext returns the logger of the request of ctx, its lines start with the request id
+func LoggerFromContext(ctx context.Context) (*log.Logger, bool) {
+	logger, ok := ctx.Value(loggerKey).(*log.Logger)
+	return logger, ok
+}
+
+// loggerFor returns the logger of the request, or fallback when the request did not go through accessLog
+func loggerFor(r *http.Request, fallback *log.Logger) *log.Logger {
+	if logger, ok := LoggerFromContext(r.Context()); ok {
+		return logger
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return fallbackLogger
+}
+
+// statusRecorder remembers the status and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(data []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(data)
+	sr.bytes += n
+	return n, err
+}
+
+// statusCode is the status sent, 200 when the handler wrote nothing
+func (sr *statusRecorder) statusCode() int {
+	if sr.status == 0 {
+		return http.StatusOK
+	}
+	return sr.status
+}
+
+// Flush keeps streamed responses flowing through the recorder
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// accessLog gives each request an id and a logger that prefixes its lines with the id,
+// and logs the method, route template, status, latency and size of the response once it is sent
+func accessLog(logger *log.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			id := req.Header.Get(requestIDHeader)
+			if !validRequestID.MatchString(id) {
+				id = newRequestID()
+			}
+			wr.Header().Set(requestIDHeader, id)
+
+			reqLogger := log.New(logger.Writer(), logger.Prefix()+"["+id+"] ", logger.Flags())
+			ctx := context.WithValue(req.Context(), requestIDKey, id)
+			ctx = context.WithValue(ctx, loggerKey, reqLogger)
+			rec := &statusRecorder{ResponseWriter: wr}
+			next.ServeHTTP(rec, req.WithContext(ctx))
+
+			reqLogger.Printf("%s %s route=%s status=%d duration=%s bytes=%d",
+				req.Method, req.URL.RequestURI(), routeTemplate(req), rec.statusCode(), time.Since(start), rec.bytes)
+		})
+	}
+}
+
+// routeTemplate returns the path template of the route that matched req, - when none did
+func routeTemplate(req *http.Request) string {
+	if current := mux.CurrentRoute(req); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "-"
+}
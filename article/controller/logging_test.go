@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer the server goroutines can write to while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.String()
+}
+
+func TestAccessLog(t *testing.T) {
+	var out syncBuffer
+	srv := httptest.NewServer(withAPIKey(New(log.New(&out, "", 0), &testConfig, testStore, nil)))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		requestID string
+		body      string
+		want      int
+		route     string
+		logged    []string
+	}{
+		{"case 01", http.MethodGet, "/v1/article/990", "trace-01", ``, http.StatusNotFound, "/v1/article/{id:[0-9]+}", nil},
+		{"case 02", http.MethodGet, "/category?format=json", "", ``, http.StatusOK, "/category", nil},
+		{"case 03", http.MethodGet, "/nowhere", "bad id with spaces", ``, http.StatusNotFound, "-", nil},
+		{"case 04", http.MethodPost, "/v1/article/bulk", "trace-04", `[{"title": "Logged"}]`, http.StatusUnprocessableEntity,
+			"/v1/article/bulk", []string{"bulk import in atomic mode: 0 created, 1 failed"}},
+		{"case 05", http.MethodPost, "/v1/category/1", "trace-05", `{"name": "News"}`, http.StatusMethodNotAllowed, "-", nil},
+		{"case 06", http.MethodGet, "/v1/article/1)", "trace-06", ``, http.StatusNotFound, "-", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			if tt.requestID != "" {
+				req.Header.Set(requestIDHeader, tt.requestID)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+
+			id := res.Header.Get(requestIDHeader)
+			if !validRequestID.MatchString(id) {
+				t.Fatalf("invalid %v %q", requestIDHeader, id)
+			}
+			if validRequestID.MatchString(tt.requestID) && id != tt.requestID {
+				t.Errorf("%v = %v, want %v", requestIDHeader, id, tt.requestID)
+			}
+
+			access := fmt.Sprintf("[%s] %s %s route=%s status=%d duration=", id, tt.method, tt.path, tt.route, tt.want)
+			logged := out.String()
+			if !strings.Contains(logged, access) {
+				t.Errorf("access log line %q not in\n%s", access, logged)
+			}
+			for _, line := range tt.logged {
+				if !strings.Contains(logged, "["+id+"] "+line) {
+					t.Errorf("handler log line %q not in\n%s", line, logged)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"github.com/femonofsky/articleMaker/article/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	httpRequests = metrics.Default.NewCounterVec("http_requests_total",
+		"Requests served, by method, route template and status.", "method", "route", "status")
+	httpRequestDuration = metrics.Default.NewHistogramVec("http_request_duration_seconds",
+		"Time to serve a request, by method, route template and status.", metrics.DefaultBuckets, "method", "route", "status")
+)
+
+// standardMethods are the methods recorded as their own label, any other is recorded as "other"
+var standardMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// methodLabel returns the method of req as a label, clients can send any token as a method so the unknown ones share one series
+func methodLabel(req *http.Request) string {
+	if standardMethods[req.Method] {
+		return req.Method
+	}
+	return "other"
+}
+
+// instrument counts the requests and their latency by route template, paths are not labels so ids do not add series.
+// Unknown methods and unmatched paths are recorded as "other" and "-" so a client cannot add series
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: wr}
+		next.ServeHTTP(rec, req)
+
+		method, route, status := methodLabel(req), routeTemplate(req), strconv.Itoa(rec.statusCode())
+		httpRequests.Inc(method, route, status)
+		httpRequestDuration.Observe(time.Since(start).Seconds(), method, route, status)
+	})
+}
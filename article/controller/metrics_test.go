@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// scrape returns the samples of /metrics of server by series
+func scrape(t *testing.T) map[string]float64 {
+	t.Helper()
+	res, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("could not send request: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("expected a Prometheus text response; got %v %v", res.Status, res.Header.Get("Content-Type"))
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("could not read body: %v", err)
+	}
+	samples := map[string]float64{}
+	for _, line := range strings.Split(string(body), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("invalid sample %q: %v", line, err)
+		}
+		samples[line[:i]] = v
+	}
+	return samples
+}
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		series []string
+	}{
+		{"case 01", http.MethodGet, "/v1/category", []string{
+			`http_requests_total{method="GET",route="/v1/category",status="200"}`,
+			`http_request_duration_seconds_count{method="GET",route="/v1/category",status="200"}`,
+			`http_request_duration_seconds_bucket{method="GET",route="/v1/category",status="200",le="+Inf"}`,
+			`db_queries_total{operation="query",status="ok"}`,
+		}},
+		{"case 02", http.MethodGet, "/v1/category/990", []string{
+			`http_requests_total{method="GET",route="/v1/category/{id:[0-9]+}",status="404"}`,
+			`http_request_duration_seconds_count{method="GET",route="/v1/category/{id:[0-9]+}",status="404"}`,
+		}},
+		{"case 03", http.MethodGet, "/category/991", []string{
+			`http_requests_total{method="GET",route="/category/{id:[0-9]+}",status="404"}`,
+		}},
+		{"case 04", http.MethodGet, "/nowhere/992", []string{
+			`http_requests_total{method="GET",route="-",status="404"}`,
+		}},
+		{"case 05", http.MethodGet, "/v1/article/bulk", []string{
+			`http_requests_total{method="GET",route="-",status="405"}`,
+			`http_request_duration_seconds_count{method="GET",route="-",status="405"}`,
+		}},
+		{"case 06", "FOO0", "/v1/category", []string{
+			`http_requests_total{method="other",route="-",status="405"}`,
+		}},
+		{"case 07", "FOO1", "/nowhere/FOO1", []string{
+			`http_requests_total{method="other",route="-",status="404"}`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := scrape(t)
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			res.Body.Close()
+
+			after := scrape(t)
+			for _, series := range tt.series {
+				if after[series] != before[series]+1 {
+					t.Errorf("%v = %v, want %v", series, after[series], before[series]+1)
+				}
+			}
+			for series := range after {
+				if strings.Contains(series, "99") || strings.Contains(series, "FOO") {
+					t.Errorf("series %v is labelled with the request path or method", series)
+				}
+			}
+		})
+	}
+
+	samples := scrape(t)
+	if samples["db_open_connections"] < 1 {
+		t.Errorf("db_open_connections = %v, want at least 1", samples["db_open_connections"])
+	}
+	if samples[`http_requests_total{method="GET",route="/metrics",status="200"}`] < 1 {
+		t.Errorf("the scrapes of /metrics are not counted")
+	}
+}
@@ -0,0 +1,440 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/femonofsky/articleMaker/article/model"
+	"net/http"
+	"time"
+)
+
+// openAPIDoc is an OpenAPI 3 document, only the parts this API needs are modelled
+type openAPIDoc struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       openAPIInfo                     `json:"info"`
+	Servers    []openAPIServer                 `json:"servers"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components openAPIComponents               `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// openAPIServer is the base of the paths, the prefix of the version
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+// securityScheme describes one way to authenticate
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+// operation describes one method of a path
+type operation struct {
+	Summary     string                 `json:"summary"`
+	Parameters  []parameter            `json:"parameters,omitempty"`
+	RequestBody *requestBody           `json:"requestBody,omitempty"`
+	Security    []map[string][]string  `json:"security,omitempty"`
+	Responses   map[string]apiResponse `json:"responses"`
+	Servers     []openAPIServer        `json:"servers,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+// apiResponse is an OpenAPI response, not to be confused with the response envelope
+type apiResponse struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Example     interface{}        `json:"example,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *int               `json:"minimum,omitempty"`
+	Maximum     *int               `json:"maximum,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Properties  map[string]*schema `json:"properties,omitempty"`
+	Items       *schema            `json:"items,omitempty"`
+}
+
+// ref points to a schema of the components
+func ref(name string) *schema {
+	return &schema{Ref: "#/components/schemas/" + name}
+}
+
+// typed returns a schema of a plain type
+func typed(t string) *schema {
+	return &schema{Type: t}
+}
+
+// list returns an array schema of items
+func list(items *schema) *schema {
+	return &schema{Type: "array", Items: items}
+}
+
+// dateTime is a date in model.DateTimeLayout, the zero date stands for no date
+func dateTime() *schema {
+	return &schema{
+		Type:        "string",
+		Pattern:     `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}$`,
+		Description: "date in the " + model.DateTimeLayout + " layout, 0001-01-01 00:00:00 when not set",
+		Example:     time.Date(2020, time.February, 25, 19, 2, 35, 0, time.UTC).Format(model.DateTimeLayout),
+	}
+}
+
+// envelope wraps the schema of the data in the response envelope
+func envelope(data *schema, paged bool) *schema {
+	s := &schema{Type: "object", Required: []string{"success", "data"}, Properties: map[string]*schema{
+		"success": typed("boolean"),
+		"data":    data,
+	}}
+	if paged {
+		s.Properties["pagination"] = ref("Pagination")
+	}
+	return s
+}
+
+// jsonBody is a request body of a json schema
+func jsonBody(s *schema, types ...string) *requestBody {
+	if len(types) == 0 {
+		types = []string{"application/json"}
+	}
+	content := map[string]mediaType{}
+	for _, t := range types {
+		content[t] = mediaType{Schema: s}
+	}
+	return &requestBody{Required: true, Content: content}
+}
+
+// responses describes the success status with its data and the error statuses of an operation
+func responses(status string, description string, data *schema, paged bool, errors ...string) map[string]apiResponse {
+	res := map[string]apiResponse{}
+	if data == nil {
+		res[status] = apiResponse{Description: description}
+	} else {
+		content := map[string]mediaType{}
+		for _, enc := range encoders {
+			if _, isCSV := enc.(csvEncoder); !isCSV || paged {
+				content[enc.mediaType()] = mediaType{Schema: envelope(data, paged)}
+			}
+		}
+		res[status] = apiResponse{Description: description, Content: content}
+	}
+	for _, code := range append(errors, "400", "406", "500", "503") {
+		res[code] = apiResponse{Description: errorDescriptions[code], Content: map[string]mediaType{
+			"application/json": {Schema: envelope(ref("Error"), false)},
+		}}
+	}
+	return res
+}
+
+// errorDescriptions of the error statuses the handlers answer
+var errorDescriptions = map[string]string{
+	"400": "the request could not be read",
+	"404": "the record does not exist",
+	"406": "none of the accepted formats can represent the response",
+	"409": "duplicate title or name, or the record is still in use",
+	"412": "If-Match does not match the current version",
+	"413": "the body of a bulk import is larger than 32 MiB or has too many articles",
+	"401": "the credentials are missing or invalid",
+	"403": "the caller may not change these articles, or this category or publisher",
+	"415": "unsupported request content type",
+	"429": "the rate limit of the client is exceeded, retry after Retry-After seconds",
+	"422": "fields do not meet their requirements",
+	"500": "the database failed, details are only logged",
+	"503": "the request ran out of time, its change was rolled back",
+}
+
+// unversioned are the servers of the operations outside /v1, the probes and the metrics
+var unversioned = []openAPIServer{{URL: "/"}}
+
+// query and path parameters shared by the operations
+var (
+	idParam      = parameter{Name: "id", In: "path", Required: true, Schema: typed("integer")}
+	nameParam    = parameter{Name: "name", In: "path", Required: true, Description: "name of the publisher", Schema: typed("string")}
+	ifMatchParam = parameter{Name: "If-Match", In: "header", Description: "ETag of the version being changed", Schema: typed("string")}
+	formatParam  = parameter{Name: "format", In: "query", Description: "response format, overrides the Accept header",
+		Schema: &schema{Type: "string", Enum: []string{"json", "xml", "yaml", "csv"}}}
+)
+
+// filterParams are the article filters read by articleFilter
+func filterParams() []parameter {
+	params := []parameter{
+		{Name: "q", In: "query", Description: "words to search in the title and body, title matches rank first", Schema: typed("string")},
+		{Name: "category", In: "query", Schema: typed("string")},
+		{Name: "publisher", In: "query", Schema: typed("string")},
+	}
+	for _, prefix := range []string{"created", "published"} {
+		for _, bound := range []struct{ suffix, description string }{
+			{"_at", "exact date"},
+			{"_from", "inclusive lower bound"},
+			{"_to", "inclusive upper bound"},
+			{"_after", "exclusive lower bound"},
+			{"_before", "exclusive upper bound"},
+		} {
+			params = append(params, parameter{Name: prefix + bound.suffix, In: "query",
+				Description: bound.description + " of " + prefix + "_at", Schema: dateTime()})
+		}
+	}
+	return params
+}
+
+// pageParams are the paging parameters read by pageFromRequest
+func pageParams() []parameter {
+	minLimit, maxPage, zero := 1, maxLimit, 0
+	return []parameter{
+		{Name: "limit", In: "query", Schema: &schema{Type: "integer", Minimum: &minLimit, Maximum: &maxPage, Example: defaultLimit}},
+		{Name: "offset", In: "query", Schema: &schema{Type: "integer", Minimum: &zero}},
+		{Name: "cursor", In: "query", Description: "next_cursor or prev_cursor of a previous page, instead of offset", Schema: typed("string")},
+		{Name: "sort", In: "query", Description: "comma separated fields, a leading - sorts descending",
+			Schema: &schema{Type: "string", Example: "-published_at,title"}},
+	}
+}
+
+// schemas of the models, the json fields follow their MarshalJSON and UnmarshalJSON
+func schemas() map[string]*schema {
+	articleInput := map[string]*schema{
+		"title":        typed("string"),
+		"body":         typed("string"),
+		"category":     {Type: "string", Description: "name of the category, created when missing"},
+		"publisher":    {Type: "string", Description: "name of the publisher, created when missing"},
+		"published_at": dateTime(),
+	}
+	article := map[string]*schema{"id": typed("integer"), "created_at": dateTime()}
+	for name, s := range articleInput {
+		article[name] = s
+	}
+	publisherInput := map[string]*schema{
+		"name":          typed("string"),
+		"display_name":  typed("string"),
+		"bio":           typed("string"),
+		"website":       {Type: "string", Format: "uri"},
+		"contact_email": {Type: "string", Format: "email"},
+	}
+	publisher := map[string]*schema{"id": typed("integer"), "created_at": dateTime(), "updated_at": dateTime()}
+	for name, s := range publisherInput {
+		publisher[name] = s
+	}
+
+	return map[string]*schema{
+		"Article":      {Type: "object", Properties: article},
+		"ArticleInput": {Type: "object", Required: []string{"title", "body", "category", "publisher"}, Properties: articleInput},
+		"Category": {Type: "object", Properties: map[string]*schema{
+			"id": typed("integer"), "name": typed("string"), "created_at": dateTime(), "updated_at": dateTime()}},
+		"CategoryInput":  {Type: "object", Required: []string{"name"}, Properties: map[string]*schema{"name": typed("string")}},
+		"Publisher":      {Type: "object", Properties: publisher},
+		"PublisherInput": {Type: "object", Required: []string{"name"}, Properties: publisherInput},
+		"Pagination": {Type: "object", Properties: map[string]*schema{
+			"total": typed("integer"), "limit": typed("integer"), "offset": typed("integer"),
+			"next_cursor": typed("string"), "prev_cursor": typed("string")}},
+		"FieldError": {Type: "object", Properties: map[string]*schema{
+			"field": typed("string"), "rule": typed("string"), "param": typed("string")}},
+		"Error": {Type: "object", Required: []string{"code", "message"}, Properties: map[string]*schema{
+			"code":    {Type: "string", Example: "validation_failed"},
+			"message": typed("string"),
+			"fields":  {Type: "array", Items: ref("FieldError"), Description: "fields that failed validation"},
+			"items":   {Type: "array", Items: ref("BulkItem"), Description: "outcome of each article of a failed bulk import"},
+		}},
+		"Readiness": {Type: "object", Properties: map[string]*schema{
+			"status": {Type: "string", Enum: []string{"starting", "ready", "unavailable", "draining"}},
+			"checks": list(&schema{Type: "object", Properties: map[string]*schema{
+				"name": typed("string"), "status": {Type: "string", Enum: []string{"ok", "failed"}},
+				"latency_ms": typed("number"), "error": typed("string")}}),
+		}},
+		"BulkItem": {Type: "object", Properties: map[string]*schema{
+			"index": typed("integer"), "success": typed("boolean"), "article": ref("Article"), "error": ref("Error")}},
+		"BulkResult": {Type: "object", Properties: map[string]*schema{
+			"mode":    {Type: "string", Enum: []string{atomicMode, bestEffortMode}},
+			"created": typed("integer"), "failed": typed("integer"), "items": list(ref("BulkItem"))}},
+	}
+}
+
+// openAPI describes every route registered by New, the operations of /v1 are rate limited,
+// the operations that change data need one of the security schemes and articles are scoped to publishers
+func openAPI() openAPIDoc {
+	doc := openAPIPaths()
+	for _, operations := range doc.Paths {
+		for method, op := range operations {
+			var codes []string
+			if op.Servers == nil {
+				codes = append(codes, "429")
+			}
+			if method != "get" {
+				op.Security = []map[string][]string{{"apiKey": {}}, {"bearerToken": {}}}
+				codes = append(codes, "401", "403")
+			}
+			for _, code := range codes {
+				op.Responses[code] = apiResponse{Description: errorDescriptions[code], Content: map[string]mediaType{
+					"application/json": {Schema: envelope(ref("Error"), false)},
+				}}
+			}
+			operations[method] = op
+		}
+	}
+	return doc
+}
+
+// openAPIPaths describes the operations and schemas of the document
+func openAPIPaths() openAPIDoc {
+	listArticles := append(append(filterParams(), pageParams()...), formatParam)
+	bulkArticles := list(ref("ArticleInput"))
+
+	return openAPIDoc{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "Article API",
+			Description: "Articles with their categories and publishers. The deprecated paths without /v1 are not described",
+			Version:     "1.0.0",
+		},
+		Servers: []openAPIServer{{URL: "/v1"}},
+		Paths: map[string]map[string]operation{
+			"/article": {
+				"get": {Summary: "List articles", Parameters: listArticles,
+					Responses: responses("200", "a page of articles", list(ref("Article")), true)},
+				"post": {Summary: "Create an article", Parameters: []parameter{formatParam}, RequestBody: jsonBody(ref("ArticleInput")),
+					Responses: responses("201", "the created article, its ETag is sent", ref("Article"), false, "409", "422")},
+			},
+			"/article/export": {
+				"get": {Summary: "Stream the articles as NDJSON", Parameters: filterParams(),
+					Responses: map[string]apiResponse{
+						"200": {Description: "one article per line", Content: map[string]mediaType{ndjsonType: {Schema: ref("Article")}}},
+						"400": {Description: errorDescriptions["400"], Content: map[string]mediaType{
+							"application/json": {Schema: envelope(ref("Error"), false)}}},
+					}},
+			},
+			"/article/bulk": {
+				"post": {Summary: "Import a batch of articles",
+					Parameters: []parameter{formatParam, {Name: "mode", In: "query",
+						Schema: &schema{Type: "string", Enum: []string{atomicMode, bestEffortMode}, Example: atomicMode}}},
+					RequestBody: jsonBody(bulkArticles, "application/json", ndjsonType),
+					Responses: func() map[string]apiResponse {
+						res := responses("201", "every article was created", ref("BulkResult"), false, "413", "415", "422")
+						res["207"] = apiResponse{Description: "best effort import where some articles failed",
+							Content: res["201"].Content}
+						return res
+					}()},
+			},
+			"/article/{id}": {
+				"get": {Summary: "Get an article", Parameters: []parameter{idParam, formatParam,
+					{Name: "If-None-Match", In: "header", Schema: typed("string")}},
+					Responses: func() map[string]apiResponse {
+						res := responses("200", "the article, its ETag is sent", ref("Article"), false, "404")
+						res["304"] = apiResponse{Description: "If-None-Match lists the current ETag"}
+						return res
+					}()},
+				"put": {Summary: "Replace an article", Parameters: []parameter{idParam, ifMatchParam, formatParam},
+					RequestBody: jsonBody(ref("ArticleInput")),
+					Responses:   responses("200", "the stored article", ref("Article"), false, "404", "409", "412", "422")},
+				"patch": {Summary: "Update an article with a JSON Merge Patch", Parameters: []parameter{idParam, ifMatchParam, formatParam},
+					RequestBody: jsonBody(typed("object"), mergePatchType, "application/json"),
+					Responses:   responses("200", "the stored article", ref("Article"), false, "404", "409", "412", "415", "422")},
+				"delete": {Summary: "Delete an article", Parameters: []parameter{idParam, ifMatchParam},
+					Responses: responses("204", "the article was deleted", nil, false, "404", "412")},
+			},
+			"/category": {
+				"get": {Summary: "List categories", Parameters: []parameter{formatParam},
+					Responses: responses("200", "every category", list(ref("Category")), false)},
+				"post": {Summary: "Create a category", Parameters: []parameter{formatParam}, RequestBody: jsonBody(ref("CategoryInput")),
+					Responses: responses("201", "the created category", ref("Category"), false, "409", "422")},
+			},
+			"/category/{id}": {
+				"get": {Summary: "Get a category", Parameters: []parameter{idParam, formatParam},
+					Responses: responses("200", "the category", ref("Category"), false, "404")},
+				"put": {Summary: "Rename a category, its articles follow", Parameters: []parameter{idParam, formatParam},
+					RequestBody: jsonBody(ref("CategoryInput")),
+					Responses:   responses("200", "the renamed category", ref("Category"), false, "404", "409", "422")},
+				"delete": {Summary: "Delete a category without articles", Parameters: []parameter{idParam},
+					Responses: responses("204", "the category was deleted", nil, false, "404", "409")},
+			},
+			"/publisher": {
+				"get": {Summary: "List publishers", Parameters: []parameter{formatParam},
+					Responses: responses("200", "every publisher", list(ref("Publisher")), false)},
+				"post": {Summary: "Create a publisher", Parameters: []parameter{formatParam}, RequestBody: jsonBody(ref("PublisherInput")),
+					Responses: responses("201", "the created publisher", ref("Publisher"), false, "409", "422")},
+			},
+			"/publisher/{id}": {
+				"get": {Summary: "Get a publisher", Parameters: []parameter{idParam, formatParam},
+					Responses: responses("200", "the publisher", ref("Publisher"), false, "404")},
+				"put": {Summary: "Update a publisher, its articles follow a new name", Parameters: []parameter{idParam, formatParam},
+					RequestBody: jsonBody(ref("PublisherInput")),
+					Responses:   responses("200", "the stored publisher", ref("Publisher"), false, "404", "409", "422")},
+				"delete": {Summary: "Delete a publisher without articles", Parameters: []parameter{idParam},
+					Responses: responses("204", "the publisher was deleted", nil, false, "404", "409")},
+			},
+			"/publisher/{name}/articles": {
+				"get": {Summary: "List the articles of a publisher", Parameters: append([]parameter{nameParam}, listArticles...),
+					Responses: responses("200", "a page of articles", list(ref("Article")), true, "404")},
+			},
+			"/openapi.json": {
+				"get": {Summary: "This document", Responses: map[string]apiResponse{
+					"200": {Description: "the OpenAPI document", Content: map[string]mediaType{"application/json": {Schema: typed("object")}}},
+				}},
+			},
+			"/healthz": {
+				"get": {Summary: "Liveness probe", Servers: unversioned, Responses: map[string]apiResponse{
+					"200": {Description: "the process serves requests", Content: map[string]mediaType{"application/json": {
+						Schema: &schema{Type: "object", Properties: map[string]*schema{"status": {Type: "string", Example: "alive"}}}}}},
+				}},
+			},
+			"/readyz": {
+				"get": {Summary: "Readiness probe, checks the database", Servers: unversioned, Responses: map[string]apiResponse{
+					"200": {Description: "the server is ready", Content: map[string]mediaType{"application/json": {Schema: ref("Readiness")}}},
+					"503": {Description: "the server starts, drains or a check failed", Content: map[string]mediaType{
+						"application/json": {Schema: ref("Readiness")}}},
+				}},
+			},
+			"/metrics": {
+				"get": {Summary: "Metrics of the process in the Prometheus text format", Servers: unversioned, Responses: map[string]apiResponse{
+					"200": {Description: "the metrics", Content: map[string]mediaType{"text/plain": {Schema: typed("string")}}},
+				}},
+			},
+		},
+		Components: openAPIComponents{
+			Schemas: schemas(),
+			SecuritySchemes: map[string]securityScheme{
+				"apiKey":      {Type: "apiKey", Name: "X-API-Key", In: "header"},
+				"bearerToken": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+}
+
+// serveOpenAPI writes the OpenAPI document as is, without the response envelope
+func serveOpenAPI(wr http.ResponseWriter, req *http.Request) {
+	wr.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(wr).Encode(openAPI()); err != nil {
+		logFailure(req, err)
+	}
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/config"
+	"github.com/gorilla/mux"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// every route of the router is documented and every documented operation is routed
+func TestOpenAPIRoutes(t *testing.T) {
+	// {id:[0-9]+} becomes {id}, trailing slash aliases are the same operation
+	variable := regexp.MustCompile(`\{([^:}]+):[^}]*\}`)
+	routed := map[string]bool{}
+	err := New(nil, &config.Config{}, testStore, nil).Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		// the preflight route has no path, the prefixes of the subrouters no handler
+		if err != nil || route.GetHandler() == nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		for _, ancestor := range ancestors {
+			if err == nil {
+				break
+			}
+			methods, err = ancestor.GetMethods()
+		}
+		if err != nil {
+			return fmt.Errorf("route %v has no method", path)
+		}
+		path = variable.ReplaceAllString(path, "{$1}")
+		if len(path) > 1 {
+			path = strings.TrimSuffix(path, "/")
+		}
+		for _, method := range methods {
+			routed[strings.ToLower(method)+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not walk the router: %v", err)
+	}
+
+	// the bare paths are the deprecated aliases of /v1, the document leaves them out
+	for operation := range routed {
+		fields := strings.SplitN(operation, " ", 2)
+		if routed[fields[0]+" /v1"+fields[1]] {
+			delete(routed, operation)
+		}
+	}
+
+	doc := openAPI()
+	documented := map[string]bool{}
+	for path, operations := range doc.Paths {
+		for method, op := range operations {
+			servers := doc.Servers
+			if op.Servers != nil {
+				servers = op.Servers
+			}
+			documented[method+" "+strings.TrimSuffix(servers[0].URL, "/")+path] = true
+		}
+	}
+
+	var missing, stale []string
+	for operation := range routed {
+		if !documented[operation] {
+			missing = append(missing, operation)
+		}
+	}
+	for operation := range documented {
+		if !routed[operation] {
+			stale = append(stale, operation)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	if len(missing) > 0 {
+		t.Errorf("routes missing from the OpenAPI document: %v", missing)
+	}
+	if len(stale) > 0 {
+		t.Errorf("documented operations without a route: %v", stale)
+	}
+}
+
+// the served document only references schemas it defines and declares every path parameter
+func TestOpenAPIDocument(t *testing.T) {
+	res, err := http.Get(server.URL + "/v1/openapi.json")
+	if err != nil {
+		t.Fatalf("could not send GET request: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %v; got %v", http.StatusOK, res.Status)
+	}
+	var doc struct {
+		OpenAPI    string                                   `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage    `json:"paths"`
+		Components struct{ Schemas map[string]interface{} } `json:"components"`
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&raw); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("could not decode document: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %v, want 3.x", doc.OpenAPI)
+	}
+
+	for _, match := range regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(string(raw), -1) {
+		if _, ok := doc.Components.Schemas[match[1]]; !ok {
+			t.Errorf("reference to undefined schema %v", match[1])
+		}
+	}
+
+	placeholder := regexp.MustCompile(`\{([^}]+)\}`)
+	for path, operations := range doc.Paths {
+		for method, data := range operations {
+			var op struct {
+				Parameters []struct{ Name, In string }
+			}
+			if err := json.Unmarshal(data, &op); err != nil {
+				t.Fatalf("could not decode %v %v: %v", method, path, err)
+			}
+			for _, match := range placeholder.FindAllStringSubmatch(path, -1) {
+				declared := false
+				for _, param := range op.Parameters {
+					declared = declared || (param.In == "path" && param.Name == match[1])
+				}
+				if !declared {
+					t.Errorf("%v %v does not declare the path parameter %v", method, path, match[1])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/model"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultLimit is the page size used when the request has no limit
+	defaultLimit = 20
+	// maxLimit caps the page size a client can ask for
+	maxLimit = 100
+	// cursorPrefix marks the content of a cursor so random strings are rejected
+	cursorPrefix = "offset:"
+)
+
+// pagination describes the page of a listing returned in the response data
+type pagination struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// page is returned by list handlers, responseHandler puts its items into data
+// and the rest into the pagination of the response
+type page struct {
+	items interface{}
+	pagination
+}
+
+// newPage wraps the items of a listing with the cursors to its neighbours
+func newPage(items interface{}, p model.Page, total int) *page {
+	pg := &page{items: items, pagination: pagination{Total: total, Limit: p.Limit, Offset: p.Offset}}
+	if p.Offset+p.Limit < total {
+		pg.NextCursor = encodeCursor(p.Offset + p.Limit)
+	}
+	if p.Offset > 0 {
+		prev := p.Offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		pg.PrevCursor = encodeCursor(prev)
+	}
+	return pg
+}
+
+// pageFromRequest reads limit, either offset or cursor, and the article sort from the query string
+func pageFromRequest(r *http.Request) (model.Page, error) {
+	p := model.Page{Limit: defaultLimit}
+	if sort := r.FormValue("sort"); sort != "" {
+		fields, err := model.ParseArticleSort(sort)
+		if err != nil {
+			return p, err
+		}
+		p.Sort = fields
+	}
+	if limit := r.FormValue("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			return p, fmt.Errorf("limit must be a positive number got: %v", limit)
+		}
+		if value > maxLimit {
+			value = maxLimit
+		}
+		p.Limit = value
+	}
+
+	offset, cursor := r.FormValue("offset"), r.FormValue("cursor")
+	switch {
+	case offset != "" && cursor != "":
+		return p, fmt.Errorf("use either offset or cursor, not both")
+	case offset != "":
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 {
+			return p, fmt.Errorf("offset must be zero or a positive number got: %v", offset)
+		}
+		p.Offset = value
+	case cursor != "":
+		value, err := decodeCursor(cursor)
+		if err != nil {
+			return p, err
+		}
+		p.Offset = value
+	}
+	return p, nil
+}
+
+// encodeCursor hides the offset of a page behind an opaque string
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the offset stored in a cursor made by encodeCursor
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), cursorPrefix) {
+		return 0, fmt.Errorf("invalid cursor got: %v", cursor)
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(data), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor got: %v", cursor)
+	}
+	return offset, nil
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/model"
+	"github.com/gorilla/mux"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// PublisherController Handler
+type PublisherController struct {
+	logger *log.Logger
+	store  model.Store
+}
+
+// GetAll Handler: list all publishers
+func (pc *PublisherController) GetAll(w io.Writer, r *http.Request) (interface{}, int, error) {
+	publishers, err := pc.store.WithContext(r.Context()).GetPublishers()
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return publishers, http.StatusOK, nil
+}
+
+// Get Handler: Get publisher using ID
+func (pc *PublisherController) Get(w io.Writer, r *http.Request) (interface{}, int, error) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v", vars["id"])
+	}
+
+	publisher, err := pc.store.WithContext(r.Context()).GetPublisher(id)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	return publisher, http.StatusOK, nil
+}
+
+// Create Handler: Create a new Publisher, only callers that may change every article can
+func (pc *PublisherController) Create(w io.Writer, r *http.Request) (interface{}, int, error) {
+	if err := authorizeShared(r); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	publisher, err := model.SerializePublisher(r.Body)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err = publisher.Validate(); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if err = pc.store.WithContext(r.Context()).CreatePublisher(publisher); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return publisher, http.StatusCreated, nil
+}
+
+// Put Handler: replace a publisher name and profile, its articles follow a new name.
+// A publisher can only change the profile of its own record
+func (pc *PublisherController) Put(w io.Writer, r *http.Request) (interface{}, int, error) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v", vars["id"])
+	}
+	publisher, err := model.SerializePublisher(r.Body)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err = publisher.Validate(); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	current, err := pc.store.WithContext(r.Context()).GetPublisher(id)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	if err = authorizePublisher(r, current.Name, publisher.Name); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+
+	if err = pc.store.WithContext(r.Context()).UpdatePublisher(id, publisher); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return publisher, http.StatusOK, nil
+}
+
+// Delete Handler: delete publisher by ID, refused while it still has articles. Only callers that may change every article can
+func (pc *PublisherController) Delete(w io.Writer, r *http.Request) (interface{}, int, error) {
+	if err := authorizeShared(r); err != nil {
+		return nil, http.StatusForbidden, err
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid Id got: %v", vars["id"])
+	}
+
+	if err = pc.store.WithContext(r.Context()).DeletePublisher(id); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return nil, http.StatusNoContent, nil
+}
+
+// Articles Handler: list the articles of a publisher, takes the same filters as ArticleController.GetAll
+func (pc *PublisherController) Articles(w io.Writer, r *http.Request) (interface{}, int, error) {
+	vars := mux.Vars(r)
+	publisher, err := pc.store.WithContext(r.Context()).GetPublisherByName(vars["name"])
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+
+	filter, err := articleFilter(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	filter.PublisherName = publisher.Name
+
+	p, err := pageFromRequest(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	articles, total, err := pc.store.WithContext(r.Context()).GetArticlesPage(filter, p)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return newPage(articles, p, total), http.StatusOK, nil
+}
+
+// newPublisher creates a new Publisher Handle of the publishers of store and their articles
+func newPublisher(logger *log.Logger, store model.Store) *PublisherController {
+	return &PublisherController{logger: logger, store: store}
+}
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/config"
+	"github.com/gorilla/mux"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter keeps the token buckets of the clients. The in memory limiter counts per server,
+// a shared backend lets several servers count together
+type RateLimiter interface {
+	// Take removes a token from the bucket of key for a request made at now
+	Take(key string, limit config.Limit, now time.Time) Decision
+}
+
+// Decision is the outcome of taking a token
+type Decision struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the wait until the next token when the request is refused
+	RetryAfter time.Duration
+	// Reset is the wait until the bucket is full again
+	Reset time.Duration
+}
+
+// bucket is the state of one token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+// refill adds the tokens earned since the last request, up to burst
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// memoryLimiter is a RateLimiter of the process memory, safe for concurrent use
+type memoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// takes counts the calls to Take between sweeps of the full buckets
+	takes int
+}
+
+// sweepEvery is the number of calls to Take between removing the buckets that refilled
+const sweepEvery = 1000
+
+// NewMemoryLimiter creates a RateLimiter that keeps the buckets in memory
+func NewMemoryLimiter() RateLimiter {
+	return &memoryLimiter{buckets: map[string]*bucket{}}
+}
+
+func (l *memoryLimiter) Take(key string, limit config.Limit, now time.Time) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.takes++; l.takes >= sweepEvery {
+		// a bucket that refilled is the same as no bucket, drop them so idle clients do not pile up
+		l.takes = 0
+		for k, b := range l.buckets {
+			if b.refill(now); b.tokens >= b.burst {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	burst := float64(burstOf(limit))
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	// the limit of a key can change with the configuration
+	b.rate, b.burst = limit.Rate, burst
+	b.refill(now)
+
+	d := Decision{Allowed: b.tokens >= 1}
+	if d.Allowed {
+		b.tokens--
+	} else {
+		d.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((burst - b.tokens) / limit.Rate)
+	return d
+}
+
+// burstOf is the size of the bucket of limit, at least one request
+func burstOf(limit config.Limit) int {
+	if limit.Burst < 1 {
+		return 1
+	}
+	return limit.Burst
+}
+
+// seconds converts a float number of seconds into a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// rateLimit limits the requests of each client to the limit of the route group,
+// a client is the subject of valid credentials or else the client IP
+func rateLimit(limiter RateLimiter, auth *authenticator, proxies trustedProxies, group string, limit config.Limit) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if limit.Rate <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+			d := limiter.Take(group+"|"+clientKey(req, auth, proxies), limit, time.Now())
+			wr.Header().Set("X-RateLimit-Limit", strconv.Itoa(burstOf(limit)))
+			wr.Header().Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			wr.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+			if !d.Allowed {
+				wr.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(d.RetryAfter)))
+				err := fmt.Errorf("rate limit exceeded, retry in %d seconds", ceilSeconds(d.RetryAfter))
+				writeResponse(wr, req, errorEncoder(req), http.StatusTooManyRequests, errorResponse(err, http.StatusTooManyRequests))
+				return
+			}
+			next.ServeHTTP(wr, req)
+		})
+	}
+}
+
+// clientKey names the bucket of a request. Credentials count only when valid,
+// otherwise a client could get a fresh bucket by sending a new made up key with each request
+func clientKey(req *http.Request, auth *authenticator, proxies trustedProxies) string {
+	if principal, err := auth.authenticate(req); err == nil {
+		return principal.Method + ":" + principal.Subject
+	}
+	return "ip:" + proxies.clientIP(req)
+}
+
+// trustedProxies are the networks of the proxies whose X-Forwarded-For is believed
+type trustedProxies []*net.IPNet
+
+// newTrustedProxies parses the IPs and CIDRs of the configuration, the invalid ones are logged and left out
+func newTrustedProxies(logger *log.Logger, entries []string) trustedProxies {
+	if logger == nil {
+		logger = fallbackLogger
+	}
+	var proxies trustedProxies
+	for _, entry := range entries {
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			logger.Printf("ignoring trusted proxy %q, it is not an IP or CIDR", entry)
+			continue
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies
+}
+
+// contains reports whether host is the IP of a trusted proxy
+func (p trustedProxies) contains(host string) bool {
+	ip := net.ParseIP(host)
+	for _, network := range p {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the IP of the client of req, the one of the connection unless it is a trusted proxy.
+// X-Forwarded-For is then read from the right, every proxy appends the address it got the request from,
+// up to the first address that is not a trusted proxy. The entries left of it can be made up by the client
+func (p trustedProxies) clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	var hops []string
+	for _, value := range req.Header["X-Forwarded-For"] {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0 && p.contains(host); i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		host = hop
+	}
+	return host
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
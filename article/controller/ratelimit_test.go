@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"bytes"
+	"github.com/femonofsky/articleMaker/article/config"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	start := time.Date(2020, time.February, 25, 19, 2, 35, 0, time.UTC)
+	limit := config.Limit{Rate: 2, Burst: 3}
+	limiter := NewMemoryLimiter()
+
+	tests := []struct {
+		name       string
+		key        string
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{"case 01", "a", 0, true, 2, 0},
+		{"case 02", "a", 0, true, 1, 0},
+		{"case 03", "a", 0, true, 0, 0},
+		{"case 04", "a", 0, false, 0, 500 * time.Millisecond},
+		{"case 05", "b", 0, true, 2, 0},
+		{"case 06", "a", 250 * time.Millisecond, false, 0, 250 * time.Millisecond},
+		{"case 07", "a", 500 * time.Millisecond, true, 0, 0},
+		{"case 08", "a", 10 * time.Second, true, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := limiter.Take(tt.key, limit, start.Add(tt.after))
+			if d.Allowed != tt.allowed || d.Remaining != tt.remaining || d.RetryAfter != tt.retryAfter {
+				t.Errorf("Take() = %+v, want allowed %v remaining %v retry after %v", d, tt.allowed, tt.remaining, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	cfg := testConfig
+	cfg.RateLimit = config.RateLimit{
+		Read:  config.Limit{Rate: 0.01, Burst: 2},
+		Write: config.Limit{Rate: 0.01, Burst: 1},
+	}
+	srv := httptest.NewServer(New(nil, &cfg, testStore, nil))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		apiKey    string
+		want      int
+		remaining string
+	}{
+		{"case 01", http.MethodGet, "/v1/category", "", http.StatusOK, "1"},
+		{"case 02", http.MethodGet, "/category", "", http.StatusOK, "0"},
+		{"case 03", http.MethodGet, "/v1/category", "", http.StatusTooManyRequests, "0"},
+		{"case 04", http.MethodGet, "/v1/category", "made-up-key", http.StatusTooManyRequests, "0"},
+		{"case 05", http.MethodGet, "/v1/category", testAPIKey, http.StatusOK, "1"},
+		{"case 06", http.MethodPost, "/v1/category", "", http.StatusUnauthorized, "0"},
+		{"case 07", http.MethodPost, "/v1/category", "", http.StatusTooManyRequests, "0"},
+		{"case 08", http.MethodDelete, "/v1/category/990", testAPIKey, http.StatusNotFound, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(`{"name": "Limited"}`))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Fatalf("expected status %v; got %v", tt.want, res.Status)
+			}
+			if remaining := res.Header.Get("X-RateLimit-Remaining"); remaining != tt.remaining {
+				t.Errorf("X-RateLimit-Remaining = %v, want %v", remaining, tt.remaining)
+			}
+			if res.Header.Get("X-RateLimit-Limit") == "" || res.Header.Get("X-RateLimit-Reset") == "" {
+				t.Errorf("missing rate limit headers")
+			}
+			if retry := res.Header.Get("Retry-After"); (tt.want == http.StatusTooManyRequests) != (retry != "") {
+				t.Errorf("Retry-After = %q with status %v", retry, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	var logs bytes.Buffer
+	proxies := newTrustedProxies(log.New(&logs, "", 0), []string{"10.0.0.0/8", "192.168.1.5", "::1", "proxy.local"})
+	if !strings.Contains(logs.String(), `ignoring trusted proxy "proxy.local"`) {
+		t.Errorf("invalid proxy not logged, got %q", logs.String())
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"case 01", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"case 02", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"case 03", "10.1.2.3:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"case 04", "10.1.2.3:5000", []string{"1.1.1.1, 198.51.100.1, 192.168.1.5"}, "198.51.100.1"},
+		{"case 05", "10.1.2.3:5000", []string{"1.1.1.1", "198.51.100.1, 10.9.9.9"}, "198.51.100.1"},
+		{"case 06", "10.1.2.3:5000", []string{"1.1.1.1, not-an-ip"}, "10.1.2.3"},
+		{"case 07", "10.1.2.3:5000", nil, "10.1.2.3"},
+		{"case 08", "[::1]:5000", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"case 09", "192.168.1.6:5000", []string{"198.51.100.1"}, "192.168.1.6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/category", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := proxies.clientIP(req); got != tt.want {
+				t.Errorf("clientIP() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/config"
+	"github.com/femonofsky/articleMaker/article/model"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"time"
+)
+
+var (
+	// legacyDeprecated is when the bare paths were deprecated in favour of /v1
+	legacyDeprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	// legacySunset is when the bare paths stop being served
+	legacySunset = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// handlers are the controllers shared by every version of the API
+type handlers struct {
+	article   *ArticleController
+	category  *CategoryController
+	publisher *PublisherController
+	auth      *authenticator
+	limiter   RateLimiter
+	limits    config.RateLimit
+	proxies   trustedProxies
+}
+
+// newHandlers initializes the controllers of store, the authentication and the rate limits of the configuration
+func newHandlers(logger *log.Logger, cfg *config.Config, store model.Store) *handlers {
+	return &handlers{
+		article:   newArticle(logger, store),
+		category:  newCategory(logger, store),
+		publisher: newPublisher(logger, store),
+		auth:      newAuthenticator(cfg.Auth),
+		limiter:   NewMemoryLimiter(),
+		limits:    cfg.RateLimit,
+		proxies:   newTrustedProxies(logger, cfg.RateLimit.TrustedProxies),
+	}
+}
+
+// registerV1 registers the routes of the first version of the API on r, reads and writes are rate limited apart
+// and the routes that change data need authentication
+func registerV1(r *mux.Router, h *handlers) {
+	// Handle All GET
+	getRouter := r.Methods(http.MethodGet).Subrouter()
+	getRouter.Use(rateLimit(h.limiter, h.auth, h.proxies, "read", h.limits.Read))
+	getRouter.HandleFunc("/article/", listHandler(h.article.GetAll))
+	getRouter.HandleFunc("/article", listHandler(h.article.GetAll))
+	getRouter.Handle("/article/export", streamHandler(h.article.Export))
+	getRouter.Handle("/article/export/", streamHandler(h.article.Export))
+	getRouter.HandleFunc("/article/{id:[0-9]+}", responseHandler(h.article.Get))
+	getRouter.HandleFunc("/article/{id:[0-9]+}/", responseHandler(h.article.Get))
+	getRouter.HandleFunc("/category/", listHandler(h.category.GetAll))
+	getRouter.HandleFunc("/category", listHandler(h.category.GetAll))
+	getRouter.HandleFunc("/category/{id:[0-9]+}", responseHandler(h.category.Get))
+	getRouter.HandleFunc("/category/{id:[0-9]+}/", responseHandler(h.category.Get))
+	getRouter.HandleFunc("/publisher/", listHandler(h.publisher.GetAll))
+	getRouter.HandleFunc("/publisher", listHandler(h.publisher.GetAll))
+	getRouter.HandleFunc("/publisher/{id:[0-9]+}", responseHandler(h.publisher.Get))
+	getRouter.HandleFunc("/publisher/{id:[0-9]+}/", responseHandler(h.publisher.Get))
+	getRouter.HandleFunc("/publisher/{name}/articles", listHandler(h.publisher.Articles))
+	getRouter.HandleFunc("/publisher/{name}/articles/", listHandler(h.publisher.Articles))
+	getRouter.HandleFunc("/openapi.json", serveOpenAPI)
+
+	// Handle All PUT
+	putRouter := r.Methods(http.MethodPut).Subrouter()
+	putRouter.Use(rateLimit(h.limiter, h.auth, h.proxies, "write", h.limits.Write), h.auth.middleware)
+	putRouter.HandleFunc("/article/{id:[0-9]+}", responseHandler(h.article.Put))
+	putRouter.HandleFunc("/article/{id:[0-9]+}/", responseHandler(h.article.Put))
+	putRouter.HandleFunc("/category/{id:[0-9]+}", responseHandler(h.category.Put))
+	putRouter.HandleFunc("/category/{id:[0-9]+}/", responseHandler(h.category.Put))
+	putRouter.HandleFunc("/publisher/{id:[0-9]+}", responseHandler(h.publisher.Put))
+	putRouter.HandleFunc("/publisher/{id:[0-9]+}/", responseHandler(h.publisher.Put))
+
+	// Handle All PATCH
+	patchRouter := r.Methods(http.MethodPatch).Subrouter()
+	patchRouter.Use(rateLimit(h.limiter, h.auth, h.proxies, "write", h.limits.Write), h.auth.middleware)
+	patchRouter.HandleFunc("/article/{id:[0-9]+}", responseHandler(h.article.Patch))
+	patchRouter.HandleFunc("/article/{id:[0-9]+}/", responseHandler(h.article.Patch))
+
+	// Handle All POST
+	postRouter := r.Methods(http.MethodPost).Subrouter()
+	postRouter.Use(rateLimit(h.limiter, h.auth, h.proxies, "write", h.limits.Write), h.auth.middleware)
+	postRouter.HandleFunc("/article/bulk", responseHandler(h.article.Bulk))
+	postRouter.HandleFunc("/article/bulk/", responseHandler(h.article.Bulk))
+	postRouter.HandleFunc("/article/", responseHandler(h.article.Create))
+	postRouter.HandleFunc("/article", responseHandler(h.article.Create))
+	postRouter.HandleFunc("/category/", responseHandler(h.category.Create))
+	postRouter.HandleFunc("/category", responseHandler(h.category.Create))
+	postRouter.HandleFunc("/publisher/", responseHandler(h.publisher.Create))
+	postRouter.HandleFunc("/publisher", responseHandler(h.publisher.Create))
+
+	// Handle All DELETE
+	deleteRouter := r.Methods(http.MethodDelete).Subrouter()
+	deleteRouter.Use(rateLimit(h.limiter, h.auth, h.proxies, "write", h.limits.Write), h.auth.middleware)
+	deleteRouter.HandleFunc("/article/{id:[0-9]+}", responseHandler(h.article.Delete))
+	deleteRouter.HandleFunc("/article/{id:[0-9]+}/", responseHandler(h.article.Delete))
+	deleteRouter.HandleFunc("/category/{id:[0-9]+}", responseHandler(h.category.Delete))
+	deleteRouter.HandleFunc("/category/{id:[0-9]+}/", responseHandler(h.category.Delete))
+	deleteRouter.HandleFunc("/publisher/{id:[0-9]+}", responseHandler(h.publisher.Delete))
+	deleteRouter.HandleFunc("/publisher/{id:[0-9]+}/", responseHandler(h.publisher.Delete))
+}
+
+// deprecated marks responses with the Deprecation (RFC 9745) and Sunset (RFC 8594) headers
+// and links the same route under the prefix of its successor
+func deprecated(prefix string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+			wr.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyDeprecated.Unix()))
+			wr.Header().Set("Sunset", legacySunset.Format(http.TimeFormat))
+			wr.Header().Set("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, prefix, req.URL.Path))
+			next.ServeHTTP(wr, req)
+		})
+	}
+}
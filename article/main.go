@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/femonofsky/articleMaker/article/config"
@@ -9,6 +10,10 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -22,28 +27,91 @@ func main() {
 
 	logger.Println("Starting the application...")
 
+	// run returns instead of exiting so its deferred DB.Close() always runs
+	if err := run(logger, *configPath); err != nil {
+		logger.Fatal(err)
+	}
+	logger.Println("Stopped")
+}
+
+// run serves the API until SIGINT or SIGTERM, then drains the requests in flight and closes the DB
+func run(logger *log.Logger, configPath string) error {
 	// load Config from file
-	cfg, err := config.FromFile(*configPath)
+	cfg, err := config.FromFile(configPath)
 	if err != nil {
-		logger.Fatal("file not found" + err.Error())
+		return fmt.Errorf("file not found %v", err)
 	}
-	// Initialize Database
-	DB, err := model.New(cfg)
+	// Initialize the store of the configured driver, the memory driver keeps the records in the process
+	store, closer, err := model.NewStore(cfg)
 	if err != nil {
-		logger.Fatalf("could not initialize DB connection : %v ", err.Error())
+		return fmt.Errorf("could not initialize DB connection : %v ", err)
 	}
+	defer closer.Close()
+
+	// Register all Controllers and its routes on the store, counting the requests in flight.
+	// /readyz reports the server starting until the migration is done
+	health := controller.NewHealth(store)
+	var inFlight sync.WaitGroup
+	srv := newServer(cfg.Server, track(&inFlight, controller.New(logger, cfg, store, health)), logger)
+
+	grace := time.Duration(cfg.Server.WithDefaults().ShutdownGrace)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	// start http server
+	errs := make(chan error, 1)
+	go func() {
+		logger.Printf("Listening on %s", srv.Addr)
+		errs <- srv.ListenAndServe()
+	}()
 
 	// Migrate all Table into DB if it doesn't exist
-	DB = model.Migrate(DB)
+	model.MigrateStore(store)
+	health.Ready()
 
-	defer DB.Close()
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-stop:
+		logger.Printf("Received %v, draining requests for up to %v", sig, grace)
+	}
 
-	// Register all Controllers and its routes
-	sm := controller.New(logger)
+	// load balancers see /readyz fail and stop sending requests before the listener closes
+	health.Drain()
+	time.Sleep(time.Duration(cfg.Server.ShutdownDelay))
 
-	// listens on the TCP network address addr
-	ADDR := fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port)
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Printf("Requests still running after the grace period, closing their connections: %v", err)
+		srv.Close()
+	}
+	// a handler keeps running when its connection is closed, its writes finish before the DB is closed
+	inFlight.Wait()
+	return nil
+}
 
-	// start http server
-	logger.Fatal(http.ListenAndServe(ADDR, sm))
+// newServer creates the http server of the address and timeouts of cfg
+func newServer(cfg config.Server, handler http.Handler, logger *log.Logger) *http.Server {
+	cfg = cfg.WithDefaults()
+	return &http.Server{
+		// listens on the TCP network address addr
+		Addr:              fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+		Handler:           handler,
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+		ErrorLog:          logger,
+	}
+}
+
+// track counts the requests next is handling in inFlight
+func track(inFlight *sync.WaitGroup, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) {
+		inFlight.Add(1)
+		defer inFlight.Done()
+		next.ServeHTTP(wr, req)
+	})
 }
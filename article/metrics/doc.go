@@ -0,0 +1,2 @@
+// Package metrics counts what the API does and writes it in the Prometheus text format
+package metrics
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	tests := []struct {
+		name   string
+		record func(r *Registry)
+		want   string
+	}{
+		{"case 01", func(r *Registry) {
+			c := r.NewCounterVec("requests_total", "Requests served.", "route", "status")
+			c.Inc("/b", "200")
+			c.Inc("/a", "404")
+			c.Add(2, "/b", "200")
+		}, `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="/a",status="404"} 1
+requests_total{route="/b",status="200"} 3
+`},
+		{"case 02", func(r *Registry) {
+			h := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+			h.Observe(0.05, "query")
+			h.Observe(0.1, "query")
+			h.Observe(0.5, "query")
+			h.Observe(3, "query")
+		}, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="query",le="0.1"} 2
+latency_seconds_bucket{op="query",le="1"} 3
+latency_seconds_bucket{op="query",le="+Inf"} 4
+latency_seconds_sum{op="query"} 3.65
+latency_seconds_count{op="query"} 4
+`},
+		{"case 03", func(r *Registry) {
+			r.NewGaugeFunc("open_connections", "Open connections.", func() float64 { return 3 })
+			r.NewCounterVec("escaped_total", "Line one\nback\\slash.", "value").Inc("say \"hi\"\n\\")
+		}, `# HELP open_connections Open connections.
+# TYPE open_connections gauge
+open_connections 3
+# HELP escaped_total Line one\nback\\slash.
+# TYPE escaped_total counter
+escaped_total{value="say \"hi\"\n\\"} 1
+`},
+		{"case 04", func(r *Registry) {
+			r.NewCounterVec("unused_total", "Never counted.")
+			h := r.NewHistogramVec("plain_seconds", "No labels.", []float64{1})
+			h.Observe(2)
+		}, `# HELP unused_total Never counted.
+# TYPE unused_total counter
+# HELP plain_seconds No labels.
+# TYPE plain_seconds histogram
+plain_seconds_bucket{le="1"} 0
+plain_seconds_bucket{le="+Inf"} 1
+plain_seconds_sum 2
+plain_seconds_count 1
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.record(r)
+			var buf bytes.Buffer
+			if _, err := r.WriteTo(&buf); err != nil {
+				t.Fatalf("WriteTo() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteTo() =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			if rec.Body.String() != tt.want || rec.Header().Get("Content-Type") != textType {
+				t.Errorf("ServeHTTP() = %q %q, want %q", rec.Header().Get("Content-Type"), rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Inc() with a missing label value did not panic")
+		}
+	}()
+	NewRegistry().NewCounterVec("requests_total", "Requests served.", "route", "status").Inc("/a")
+}
@@ -4,8 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"github.com/go-playground/validator"
+	"github.com/jinzhu/gorm"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -14,13 +15,14 @@ type Article struct {
 	ID            uint      `gorm:"primary_key;auto_increment"`
 	Title         string    `sql:"unique;unique_index;not null" json:"title" validate:"required"`
 	Body          string    `sql:"not null" json:"body" validate:"required"`
-	Category      Category  `gorm:"association_foreignKey:CategoryName" json:"-"`
+	Category      Category  `gorm:"association_foreignKey:CategoryName" json:"-" validate:"-"`
 	CategoryName  string    `json:"category" validate:"required"`
-	Publisher     Publisher `gorm:"association_foreignKey:PublisherName" json:"-"`
+	Publisher     Publisher `gorm:"association_foreignKey:PublisherName" json:"-" validate:"-"`
 	PublisherName string    `json:"publisher" validate:"required"`
 	CreatedAt     time.Time `json:"created_at"`
 	PublishedAt   time.Time `json:"published_at" `
 	UpdatedAt     time.Time `json:"-"`
+	Version       uint      `gorm:"not null;default:1" json:"-"`
 }
 
 // UnmarshalJSON parses the json string in the custom format
@@ -44,7 +46,7 @@ func (article *Article) UnmarshalJSON(data []byte) (err error) {
 	if auxArticle.PublishedAt != "" {
 		publishedAt, err := time.Parse(DateTimeLayout, auxArticle.PublishedAt)
 		if err != nil {
-			return fmt.Errorf("invalid format use (%v format) : %v", DateTimeLayout, err)
+			return &ValidationError{Fields: []FieldError{{Field: "published_at", Rule: "datetime", Param: DateTimeLayout}}}
 		}
 		article.PublishedAt = publishedAt
 	}
@@ -75,20 +77,31 @@ func (article *Article) MarshalJSON() ([]byte, error) {
 
 // Validate: check if all Article fields met requirements
 func (article *Article) Validate() error {
-	validate := validator.New()
-	return validate.Struct(article)
+	return validateStruct(article)
 }
 
-// BeforeSave is triggered by Gorm before saving the article
-func (article *Article) BeforeSave() error {
+// ETag identifies the stored version of the article for conditional requests
+func (article *Article) ETag() string {
+	return fmt.Sprintf(`"%d-%d"`, article.ID, article.Version)
+}
+
+// BeforeCreate is triggered by Gorm before inserting the article
+func (article *Article) BeforeCreate() error {
+	article.Version = 1
+	return nil
+}
+
+// BeforeSave is triggered by Gorm before saving the article,
+// tx is the handle of the save so it also works inside a transaction
+func (article *Article) BeforeSave(tx *gorm.DB) error {
 	category := Category{}
-	if err := Db.FirstOrCreate(&category, Category{Name: article.CategoryName}).Error; err != nil {
+	if err := tx.FirstOrCreate(&category, Category{Name: article.CategoryName}).Error; err != nil {
 		return fmt.Errorf("could not reference category got: %v", err)
 	}
 
 	article.Category = category
 	publisher := Publisher{}
-	if err := Db.FirstOrCreate(&publisher, Publisher{Name: article.PublisherName}).Error; err != nil {
+	if err := tx.FirstOrCreate(&publisher, Publisher{Name: article.PublisherName}).Error; err != nil {
 		return fmt.Errorf("could not reference category got: %v", err)
 	}
 
@@ -101,51 +114,227 @@ func (article *Article) BeforeSave() error {
 // Articles of a collection of Article
 type Articles []*Article
 
-// GetArticles returns a slice of articles
-func GetArticles(article Article) (Articles, error) {
+// Page selects a window of a listing, a Limit of zero means no limit
+type Page struct {
+	Limit  int
+	Offset int
+	Sort   []SortField
+}
+
+// SortField orders a listing by one column
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// articleSortColumns maps the json field names an article listing can be sorted by to their columns
+var articleSortColumns = map[string]string{
+	"id":           "id",
+	"title":        "title",
+	"category":     "category_name",
+	"publisher":    "publisher_name",
+	"created_at":   "created_at",
+	"published_at": "published_at",
+}
+
+// ErrUnknownSortField is returned when sorting by a field that is not allowed
+var ErrUnknownSortField = fmt.Errorf("unknown sort field")
+
+// ParseArticleSort parses a comma separated list of article fields, a leading "-" sorts descending.
+// e.g "-published_at,title"
+func ParseArticleSort(value string) ([]SortField, error) {
+	var fields []SortField
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field := SortField{}
+		if strings.HasPrefix(name, "-") {
+			field.Desc = true
+			name = name[1:]
+		}
+		column, ok := articleSortColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("%v: %q", ErrUnknownSortField, name)
+		}
+		field.Column = column
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// order applies the sort fields to the query followed by the search rank if any,
+// id breaks ties so pages are stable
+func order(query *gorm.DB, sort []SortField, rank *gorm.SqlExpr) *gorm.DB {
+	byID := false
+	for _, field := range sort {
+		if field.Desc {
+			query = query.Order(field.Column + " desc")
+		} else {
+			query = query.Order(field.Column)
+		}
+		byID = byID || field.Column == "id"
+	}
+	if rank != nil {
+		query = query.Order(rank)
+	}
+	if !byID {
+		query = query.Order("id")
+	}
+	return query
+}
+
+// GetArticlesPage returns one page of the articles matching the filter
+// together with the total number of matching articles
+func (s *GormStore) GetArticlesPage(filter ArticleFilter, page Page) (Articles, int, error) {
+	total := 0
+	query := filter.apply(s.db.Model(&Article{}))
+	if err := s.done(); err != nil {
+		return nil, 0, err
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, storage(err)
+	}
+	if err := s.done(); err != nil {
+		return nil, 0, err
+	}
+
+	if page.Limit > 0 {
+		query = query.Limit(page.Limit)
+	}
+	if page.Offset > 0 {
+		query = query.Offset(page.Offset)
+	}
 	articles := Articles{}
-	if err := Db.Find(&articles, article).Error; err != nil {
-		return nil, err
+	if err := order(query, page.Sort, filter.rank()).Find(&articles).Error; err != nil {
+		return nil, 0, storage(err)
 	}
-	return articles, nil
+	return articles, total, nil
 }
 
-// CreateArticle create new  Article
-func CreateArticle(article *Article) error {
-	arr, err := GetArticle(Article{Title: article.Title})
-	if arr != nil || err == nil {
-		return fmt.Errorf("title aleady exists")
+// EachArticle calls fn with every article matching the filter, in the order of GetArticlesPage without a sort.
+// Rows are read from a cursor one at a time so the articles are never held in memory together,
+// the first error of fn stops the iteration and is returned
+func (s *GormStore) EachArticle(filter ArticleFilter, fn func(*Article) error) error {
+	rows, err := order(filter.apply(s.db.Model(&Article{})), nil, filter.rank()).Rows()
+	if err != nil {
+		return storage(err)
 	}
-	if err := Db.Create(&article).Error; err != nil {
-		return err
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := s.done(); err != nil {
+			return err
+		}
+		article := &Article{}
+		if err := s.db.ScanRows(rows, article); err != nil {
+			return storage(err)
+		}
+		if err := fn(article); err != nil {
+			return err
+		}
+	}
+	return storage(rows.Err())
+}
+
+// ErrTitleExists is returned when another article already has the title
+var ErrTitleExists = newError(ErrConflict, "title already exists")
+
+// titleTaken returns ErrTitleExists when an article other than id has the title
+func (s *GormStore) titleTaken(title string, id uint) error {
+	other := &Article{}
+	err := s.db.Where("title = ?", title).First(other).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return storage(err)
+	}
+	if other.ID != id {
+		return ErrTitleExists
 	}
 	return nil
 }
 
-// UpdateArticle update Article using ID to check if the article exist
-func UpdateArticle(id int, article *Article) error {
-	arr, err := GetArticle(Article{ID: uint(id)})
-	if arr == nil || err != nil {
+// CreateArticle create new  Article
+func (s *GormStore) CreateArticle(article *Article) error {
+	if err := s.titleTaken(article.Title, 0); err != nil {
 		return err
 	}
+	err := s.transaction(func(tx *gorm.DB) error {
+		return tx.Create(&article).Error
+	})
+	return storage(err)
+}
 
-	if err = Db.Debug().Model(arr).Update(article).First(&article).Error; err != nil {
+// ErrArticleChanged is returned when the stored article is not at the version the caller expects
+var ErrArticleChanged = fmt.Errorf("article was changed since it was read")
+
+// UpdateArticle replaces the Article with the given ID, every field is written including zero values.
+// version is the stored version the caller expects, zero skips the check.
+// article is reloaded with the stored values
+func (s *GormStore) UpdateArticle(id int, article *Article, version uint) error {
+	arr, err := s.GetArticle(id)
+	if arr == nil || err != nil {
+		return err
+	}
+	if version == 0 {
+		version = arr.Version
+	}
+	if err := s.titleTaken(article.Title, arr.ID); err != nil {
 		return err
 	}
 
+	arr.Title = article.Title
+	arr.Body = article.Body
+	arr.CategoryName = article.CategoryName
+	arr.PublisherName = article.PublisherName
+	arr.PublishedAt = article.PublishedAt
+	arr.Version = version + 1
+	err = s.transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, arr.ID, version); err != nil {
+			return err
+		}
+		return tx.Save(arr).Error
+	})
+	if err != nil {
+		return storage(err)
+	}
+
+	return storage(s.db.First(article, arr.ID).Error)
+}
+
+// claimVersion moves the article from version to the next one.
+// The row stays locked until the transaction ends so a concurrent writer sees the new version
+func claimVersion(tx *gorm.DB, id uint, version uint) error {
+	res := tx.Model(&Article{}).Where("id = ? AND version = ?", id, version).UpdateColumn("version", version+1)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrArticleChanged
+	}
 	return nil
 }
 
-// DeleteArticle Article using article id
-func DeleteArticle(id int) error {
-	articles, err := GetArticle(Article{ID: uint(id)})
+// DeleteArticle Article using article id,
+// version is the stored version the caller expects, zero skips the check
+func (s *GormStore) DeleteArticle(id int, version uint) error {
+	articles, err := s.GetArticle(id)
 	if articles == nil || err != nil {
 		return err
 	}
-	if err = Db.Delete(&articles).Error; err != nil {
-		return err
+	if version == 0 {
+		version = articles.Version
 	}
-	return nil
+	err = s.transaction(func(tx *gorm.DB) error {
+		if err := claimVersion(tx, articles.ID, version); err != nil {
+			return err
+		}
+		return tx.Delete(&articles).Error
+	})
+	return storage(err)
 }
 
 // Serialize convert request into Article object
@@ -155,6 +344,9 @@ func Serialize(r io.ReadCloser) (*Article, error) {
 
 	// read the JSON-encoded value and decode into slice of Articles
 	if err := json.NewDecoder(r).Decode(article); err != nil {
+		if verr, ok := err.(*ValidationError); ok {
+			return nil, verr
+		}
 		return nil, fmt.Errorf("unable to decode json request body: %v", err)
 	}
 	defer r.Close()
@@ -163,13 +355,19 @@ func Serialize(r io.ReadCloser) (*Article, error) {
 }
 
 // ErrArticleNotFound article not found error
-var ErrArticleNotFound = fmt.Errorf("article not found")
+var ErrArticleNotFound = newError(ErrNotFound, "article not found")
 
 // GetArticle get article by ID
-func GetArticle(query interface{}) (*Article, error) {
+func (s *GormStore) GetArticle(id int) (*Article, error) {
+	if err := s.done(); err != nil {
+		return nil, err
+	}
 	articles := &Article{}
-	if err := Db.First(articles, query).Error; err != nil {
-		return nil, ErrArticleNotFound
+	if err := s.db.First(articles, id).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrArticleNotFound
+		}
+		return nil, storage(err)
 	}
 
 	return articles, nil
@@ -2,6 +2,7 @@ package model
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/femonofsky/articleMaker/article/config"
 	"io/ioutil"
 	"log"
@@ -11,6 +12,9 @@ import (
 	"time"
 )
 
+// store is the database of the tests
+var store *GormStore
+
 func TestMain(m *testing.M) {
 	cfg := config.Config{
 		DB: config.DB{
@@ -25,6 +29,7 @@ func TestMain(m *testing.M) {
 	}
 	defer db.Close()
 	db.Debug().AutoMigrate(&Article{}, &Category{}, &Publisher{})
+	store = NewGormStore(db)
 
 	log.Println("Finished loading Database")
 	if err := refreshAllTable(); err != nil {
@@ -36,16 +41,22 @@ func TestMain(m *testing.M) {
 
 }
 
+// getArticles returns every article of s matching the filter, unpaged
+func getArticles(s Store, filter ArticleFilter) (Articles, error) {
+	articles, _, err := s.GetArticlesPage(filter, Page{})
+	return articles, err
+}
+
 // Clear all DB tables
 func refreshAllTable() error {
 	// Drop Table if Exists
-	err := Db.Debug().DropTableIfExists(&Article{}, &Category{}, &Publisher{}).Error
+	err := store.db.Debug().DropTableIfExists(&Article{}, &Category{}, &Publisher{}).Error
 	if err != nil {
 		return err
 	}
 
 	// Migrate All table
-	err = Db.Debug().AutoMigrate(Article{}, &Category{}, &Publisher{}).Error
+	err = store.db.Debug().AutoMigrate(Article{}, &Category{}, &Publisher{}).Error
 	if err != nil {
 		return err
 	}
@@ -140,7 +151,7 @@ func TestCreateArticle(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := CreateArticle(&tt.args)
+			err := store.CreateArticle(&tt.args)
 			if err != nil && !tt.wantErr {
 				t.Errorf("unable validate data:%v", err)
 			}
@@ -170,8 +181,8 @@ func TestDeleteArticle(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			arti := tt.args
-			CreateArticle(&arti)
-			err := DeleteArticle(int(arti.ID))
+			store.CreateArticle(&arti)
+			err := store.DeleteArticle(int(arti.ID), 0)
 			if err != nil && !tt.wantErr {
 				t.Errorf("unable validate data:%v", err)
 			}
@@ -202,8 +213,8 @@ func TestUpdateArticle(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			arti := tt.args
-			CreateArticle(&arti)
-			err := UpdateArticle(int(arti.ID), &tt.want)
+			store.CreateArticle(&arti)
+			err := store.UpdateArticle(int(arti.ID), &tt.want, 0)
 			if err != nil && !tt.wantErr {
 				t.Errorf("unable validate data:%v", err)
 			}
@@ -225,7 +236,7 @@ func TestGetArticles(t *testing.T) {
 		PublisherName: "femonofsky"}, &Article{Title: "Love of Money", Body: "Love of Money", CategoryName: "Money",
 		PublisherName: "tunde"}}
 	for _, article := range articles {
-		err := CreateArticle(article)
+		err := store.CreateArticle(article)
 		if err != nil {
 			t.Errorf("unable to create new article %v", err)
 		}
@@ -233,18 +244,18 @@ func TestGetArticles(t *testing.T) {
 
 	tests := []struct {
 		name  string
-		arg   Article
+		arg   ArticleFilter
 		count int
 	}{
-		{"case 01", Article{}, 2},
-		{"case 02", Article{Title: "Love of Money"}, 1},
-		{"case 03", Article{CategoryName: "Money"}, 1},
-		{"case 04", Article{CategoryName: "Money23"}, 0},
+		{"case 01", ArticleFilter{}, 2},
+		{"case 02", ArticleFilter{Search: "Love of Money"}, 1},
+		{"case 03", ArticleFilter{CategoryName: "Money"}, 1},
+		{"case 04", ArticleFilter{CategoryName: "Money23"}, 0},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := GetArticles(tt.arg)
+			got, err := getArticles(store, tt.arg)
 			if err != nil {
 				t.Errorf("unable to validate data:%v", err)
 			}
@@ -255,3 +266,232 @@ func TestGetArticles(t *testing.T) {
 		})
 	}
 }
+
+// Get a page of Articles
+func TestGetArticlesPage(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	for i := 0; i < 5; i++ {
+		article := Article{Title: fmt.Sprintf("Money %d", i), Body: "Money is good",
+			CategoryName: "social", PublisherName: "femonofsky"}
+		if i == 4 {
+			article.CategoryName = "Money"
+		}
+		if err := store.CreateArticle(&article); err != nil {
+			t.Fatalf("unable to create new article %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		arg   ArticleFilter
+		page  Page
+		count int
+		total int
+		first string
+	}{
+		{"case 01", ArticleFilter{}, Page{Limit: 2}, 2, 5, "Money 0"},
+		{"case 02", ArticleFilter{}, Page{Limit: 2, Offset: 4}, 1, 5, "Money 4"},
+		{"case 03", ArticleFilter{CategoryName: "social"}, Page{Limit: 3, Offset: 3}, 1, 4, "Money 3"},
+		{"case 04", ArticleFilter{}, Page{}, 5, 5, "Money 0"},
+		{"case 05", ArticleFilter{}, Page{Limit: 2, Offset: 10}, 0, 5, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := store.GetArticlesPage(tt.arg, tt.page)
+			if err != nil {
+				t.Fatalf("unable to get page:%v", err)
+			}
+			if len(got) != tt.count || total != tt.total {
+				t.Errorf("page is not working got: %v of %v want: %v of %v", len(got), total, tt.count, tt.total)
+			}
+			if len(got) > 0 && got[0].Title != tt.first {
+				t.Errorf("page starts at %v want: %v", got[0].Title, tt.first)
+			}
+		})
+	}
+}
+
+// Parse article sort
+func TestParseArticleSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		want    []SortField
+		wantErr bool
+	}{
+		{"case 01", "-published_at,title", []SortField{{"published_at", true}, {"title", false}}, false},
+		{"case 02", "category, -id", []SortField{{"category_name", false}, {"id", true}}, false},
+		{"case 03", "", nil, false},
+		{"case 04", "body", nil, true},
+		{"case 05", "title,-updated_at", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseArticleSort(tt.args)
+			if err != nil && !tt.wantErr {
+				t.Errorf("unable to parse sort:%v", err)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error parsing %q", tt.args)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseArticleSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Sort a page of Articles
+func TestGetArticlesPageSorted(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	articles := Articles{
+		&Article{Title: "B", Body: "Money", CategoryName: "social", PublisherName: "tunde"},
+		&Article{Title: "A", Body: "Money", CategoryName: "social", PublisherName: "tunde"},
+		&Article{Title: "C", Body: "Money", CategoryName: "money", PublisherName: "tunde"},
+	}
+	for _, article := range articles {
+		if err := store.CreateArticle(article); err != nil {
+			t.Fatalf("unable to create new article %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		sort string
+		want []string
+	}{
+		{"case 01", "", []string{"B", "A", "C"}},
+		{"case 02", "title", []string{"A", "B", "C"}},
+		{"case 03", "-title", []string{"C", "B", "A"}},
+		{"case 04", "category", []string{"C", "B", "A"}},
+		{"case 05", "-category,-id", []string{"A", "B", "C"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort, err := ParseArticleSort(tt.sort)
+			if err != nil {
+				t.Fatalf("unable to parse sort:%v", err)
+			}
+			got, _, err := store.GetArticlesPage(ArticleFilter{}, Page{Sort: sort})
+			if err != nil {
+				t.Fatalf("unable to get page:%v", err)
+			}
+			titles := []string{}
+			for _, article := range got {
+				titles = append(titles, article.Title)
+			}
+			if !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("sorted titles = %v, want %v", titles, tt.want)
+			}
+		})
+	}
+}
+
+// Update Article replaces every field
+func TestUpdateArticleReplaces(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := Article{Title: "Money", Body: "Money is good", CategoryName: "social",
+		PublisherName: "femonofsky", PublishedAt: time.Date(2020, time.February, 25, 0, 0, 0, 0, time.UTC)}
+	if err := store.CreateArticle(&article); err != nil {
+		t.Fatalf("unable to create new article %v", err)
+	}
+
+	replacement := Article{Title: "Money", Body: "Money is bad", CategoryName: "news", PublisherName: "tunde"}
+	if err := store.UpdateArticle(int(article.ID), &replacement, 0); err != nil {
+		t.Fatalf("unable to update article %v", err)
+	}
+	if replacement.ID != article.ID || !replacement.PublishedAt.IsZero() || replacement.CreatedAt.IsZero() {
+		t.Errorf("stored article was not returned got: %+v", replacement)
+	}
+	if _, err := store.getCategory(Category{Name: "news"}); err != nil {
+		t.Errorf("category was not created on update: %v", err)
+	}
+}
+
+// Update and Delete Article at an expected version
+func TestArticleVersion(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := Article{Title: "Money", Body: "Money is good", CategoryName: "social", PublisherName: "femonofsky"}
+	if err := store.CreateArticle(&article); err != nil {
+		t.Fatalf("unable to create new article %v", err)
+	}
+	if article.Version != 1 {
+		t.Fatalf("new article version = %v, want 1", article.Version)
+	}
+	etag := article.ETag()
+
+	update := Article{Title: "Money", Body: "Money is bad", CategoryName: "social", PublisherName: "femonofsky"}
+	if err := store.UpdateArticle(int(article.ID), &update, 1); err != nil {
+		t.Fatalf("unable to update article %v", err)
+	}
+	if update.Version != 2 || update.ETag() == etag {
+		t.Errorf("version was not moved got: %v %v", update.Version, update.ETag())
+	}
+
+	stale := Article{Title: "Money", Body: "Money is stale", CategoryName: "social", PublisherName: "femonofsky"}
+	if err := store.UpdateArticle(int(article.ID), &stale, 1); err != ErrArticleChanged {
+		t.Errorf("UpdateArticle() with stale version = %v, want %v", err, ErrArticleChanged)
+	}
+	if err := store.DeleteArticle(int(article.ID), 1); err != ErrArticleChanged {
+		t.Errorf("DeleteArticle() with stale version = %v, want %v", err, ErrArticleChanged)
+	}
+	if err := store.DeleteArticle(int(article.ID), 2); err != nil {
+		t.Errorf("unable to delete article %v", err)
+	}
+}
+
+// Iterate the articles matching a filter
+func TestEachArticle(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	for _, title := range []string{"Money", "Love", "Money matters"} {
+		article := Article{Title: title, Body: title + " is good", CategoryName: "social", PublisherName: "femonofsky"}
+		if err := store.CreateArticle(&article); err != nil {
+			t.Fatalf("unable to create new article %v", err)
+		}
+	}
+	stop := fmt.Errorf("stop")
+
+	tests := []struct {
+		name    string
+		filter  ArticleFilter
+		fail    error
+		want    []string
+		wantErr error
+	}{
+		{"case 01", ArticleFilter{}, nil, []string{"Money", "Love", "Money matters"}, nil},
+		{"case 02", ArticleFilter{Search: "money"}, nil, []string{"Money", "Money matters"}, nil},
+		{"case 03", ArticleFilter{}, stop, []string{"Money"}, stop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := store.EachArticle(tt.filter, func(article *Article) error {
+				got = append(got, article.Title)
+				return tt.fail
+			})
+			if err != tt.wantErr {
+				t.Errorf("EachArticle() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EachArticle() visited %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jinzhu/gorm"
+)
+
+// titleChunk keeps the title lookups of an import under the bound parameter limit of sqlite
+const titleChunk = 500
+
+// ErrTitleRepeated is returned for an article of a batch whose title an earlier article of the batch has
+var ErrTitleRepeated = newError(ErrConflict, "title is repeated in the batch")
+
+// ErrUnreadable is returned for the nil entries of a batch, the caller could not read those articles
+var ErrUnreadable = fmt.Errorf("article could not be read")
+
+// ErrImportAborted is returned for the valid articles of an atomic import that failed on another article
+var ErrImportAborted = fmt.Errorf("not imported, another article of the batch failed")
+
+// ImportArticles creates a batch of articles, the returned errors line up with articles and are nil for created ones.
+// Each article is validated and its title checked against the rest of the batch and the stored articles.
+// When atomic is set nothing is stored unless every article can be, otherwise every valid article is stored
+func (s *GormStore) ImportArticles(articles Articles, atomic bool) []error {
+	if err := s.done(); err != nil {
+		return cancelImport(articles, err)
+	}
+	errs := s.checkImport(articles)
+	if !atomic {
+		for i, article := range articles {
+			if errs[i] == nil {
+				errs[i] = storage(s.transaction(func(tx *gorm.DB) error {
+					return tx.Create(article).Error
+				}))
+			}
+		}
+		return errs
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return abortImport(articles, errs)
+		}
+	}
+	err := s.transaction(func(tx *gorm.DB) error {
+		for i, article := range articles {
+			if err := tx.Create(article).Error; err != nil {
+				errs[i] = storage(err)
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrCanceled) {
+		return cancelImport(articles, err)
+	}
+	if err != nil {
+		return abortImport(articles, errs)
+	}
+	return errs
+}
+
+// checkImport validates the articles of a batch and looks for titles that are repeated or already stored
+func (s *GormStore) checkImport(articles Articles) []error {
+	errs, titles := checkBatch(articles)
+	stored := map[string]bool{}
+	for start := 0; start < len(titles); start += titleChunk {
+		end := start + titleChunk
+		if end > len(titles) {
+			end = len(titles)
+		}
+		var existing []string
+		if err := s.db.Model(&Article{}).Where("title IN (?)", titles[start:end]).Pluck("title", &existing).Error; err != nil {
+			for i := range errs {
+				if errs[i] == nil {
+					errs[i] = storage(err)
+				}
+			}
+			return errs
+		}
+		for _, title := range existing {
+			stored[title] = true
+		}
+	}
+	for i, article := range articles {
+		if errs[i] == nil && stored[article.Title] {
+			errs[i] = ErrTitleExists
+		}
+	}
+	return errs
+}
+
+// abortImport marks the articles without an error as aborted and clears what the rolled back inserts assigned
+func abortImport(articles Articles, errs []error) []error {
+	for i, article := range articles {
+		if errs[i] == nil {
+			errs[i] = ErrImportAborted
+		}
+		if article != nil {
+			article.ID, article.Version = 0, 0
+		}
+	}
+	return errs
+}
+
+// cancelImport fails every article of a batch with the ErrCanceled error err, none of them was stored
+func cancelImport(articles Articles, err error) []error {
+	errs := make([]error, len(articles))
+	for i := range errs {
+		errs[i] = err
+	}
+	return abortImport(articles, errs)
+}
+
+// checkBatch validates the articles of a batch and looks for titles repeated within it.
+// It returns the errors lined up with articles and the titles of the articles without one
+func checkBatch(articles Articles) ([]error, []string) {
+	errs := make([]error, len(articles))
+	seen := map[string]bool{}
+	var titles []string
+	for i, article := range articles {
+		if article == nil {
+			errs[i] = ErrUnreadable
+			continue
+		}
+		if err := article.Validate(); err != nil {
+			errs[i] = err
+			continue
+		}
+		if seen[article.Title] {
+			errs[i] = ErrTitleRepeated
+			continue
+		}
+		seen[article.Title] = true
+		titles = append(titles, article.Title)
+	}
+	return errs, titles
+}
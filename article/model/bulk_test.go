@@ -0,0 +1,70 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+// Import a batch of articles atomically or best effort
+func TestImportArticles(t *testing.T) {
+	newBatch := func() Articles {
+		return Articles{
+			{Title: "Money", Body: "Money is good", CategoryName: "social", PublisherName: "femonofsky"},
+			{Title: "Love", Body: "Love is good", CategoryName: "social", PublisherName: "tunde"},
+			{Title: "Money", Body: "Money is bad", CategoryName: "social", PublisherName: "tunde"},
+			{Title: "Stored", Body: "Stored is good", CategoryName: "social", PublisherName: "tunde"},
+			{Title: "", Body: "No title", CategoryName: "social", PublisherName: "tunde"},
+			nil,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		atomic  bool
+		batch   Articles
+		want    []error
+		created int
+	}{
+		{"case 01", true, newBatch()[:2], []error{nil, nil}, 2},
+		{"case 02", true, newBatch(), []error{ErrImportAborted, ErrImportAborted, ErrTitleRepeated,
+			ErrTitleExists, ErrValidation, ErrUnreadable}, 0},
+		{"case 03", false, newBatch(), []error{nil, nil, ErrTitleRepeated,
+			ErrTitleExists, ErrValidation, ErrUnreadable}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := refreshAllTable(); err != nil {
+				t.Fatal("unable to refreshTable")
+			}
+			stored := Article{Title: "Stored", Body: "Stored is good", CategoryName: "social", PublisherName: "tunde"}
+			if err := store.CreateArticle(&stored); err != nil {
+				t.Fatalf("unable to create new article %v", err)
+			}
+
+			errs := store.ImportArticles(tt.batch, tt.atomic)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("ImportArticles() returned %d errors, want %d", len(errs), len(tt.want))
+			}
+			for i, err := range errs {
+				if !errors.Is(err, tt.want[i]) {
+					t.Errorf("article %d error = %v, want %v", i, err, tt.want[i])
+				}
+				if err == nil && tt.batch[i].ID == 0 {
+					t.Errorf("article %d was not given an ID", i)
+				}
+				if err != nil && tt.batch[i] != nil && tt.batch[i].ID != 0 {
+					t.Errorf("failed article %d kept ID %v", i, tt.batch[i].ID)
+				}
+			}
+
+			articles, err := getArticles(store, ArticleFilter{})
+			if err != nil {
+				t.Fatalf("unable to get articles %v", err)
+			}
+			if len(articles) != tt.created+1 {
+				t.Errorf("stored %d articles, want %d", len(articles)-1, tt.created)
+			}
+		})
+	}
+}
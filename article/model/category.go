@@ -1,9 +1,152 @@
 package model
 
-import "github.com/jinzhu/gorm"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"io"
+)
 
 //  Category Defines the structure for an Category
 type Category struct {
 	gorm.Model
-	Name string `sql:"unique;not null" json:"name"`
+	Name string `sql:"unique;not null" json:"name" validate:"required"`
+}
+
+// UnmarshalJSON parses the json string, only the name can be set by a client
+func (category *Category) UnmarshalJSON(data []byte) error {
+	var auxCategory struct {
+		Name string `json:"name"`
+	}
+
+	dec := json.NewDecoder(bytes.NewBuffer(data))
+	if err := dec.Decode(&auxCategory); err != nil {
+		return fmt.Errorf("unable to decode %v", err)
+	}
+	category.Name = auxCategory.Name
+	return nil
+}
+
+// MarshalJSON writes the category in the custom format
+func (category *Category) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		ID        uint   `json:"id"`
+		Name      string `json:"name"`
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+	}{
+		ID:        category.ID,
+		Name:      category.Name,
+		CreatedAt: category.CreatedAt.Format(DateTimeLayout),
+		UpdatedAt: category.UpdatedAt.Format(DateTimeLayout),
+	})
+}
+
+// Validate: check if all Category fields met requirements
+func (category *Category) Validate() error {
+	return validateStruct(category)
+}
+
+// Categories of a collection of Category
+type Categories []*Category
+
+// ErrCategoryNotFound category not found error
+var ErrCategoryNotFound = newError(ErrNotFound, "category not found")
+
+// ErrCategoryExists is returned when another category already has the name
+var ErrCategoryExists = newError(ErrConflict, "category name already exists")
+
+// ErrCategoryInUse is returned when deleting a category that still has articles
+var ErrCategoryInUse = newError(ErrConflict, "category still has articles, move or delete them first")
+
+// GetCategories returns all categories ordered by name
+func (s *GormStore) GetCategories() (Categories, error) {
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	categories := Categories{}
+	if err := s.db.Order("name").Find(&categories).Error; err != nil {
+		return nil, storage(err)
+	}
+	return categories, nil
+}
+
+// GetCategory get category by ID
+func (s *GormStore) GetCategory(id int) (*Category, error) {
+	return s.getCategory(id)
+}
+
+// getCategory get category by the primary key or the fields of query
+func (s *GormStore) getCategory(query interface{}) (*Category, error) {
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	category := &Category{}
+	if err := s.db.First(category, query).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, storage(err)
+	}
+	return category, nil
+}
+
+// CreateCategory create new Category
+func (s *GormStore) CreateCategory(category *Category) error {
+	if _, err := s.getCategory(Category{Name: category.Name}); err != ErrCategoryNotFound {
+		if err == nil {
+			return ErrCategoryExists
+		}
+		return err
+	}
+	return storage(s.transaction(func(tx *gorm.DB) error {
+		return tx.Create(category).Error
+	}))
+}
+
+// UpdateCategory renames the category with the given ID.
+// Articles follow the new name through the category_name foreign key (ON UPDATE CASCADE) and move to their next version
+func (s *GormStore) UpdateCategory(id int, category *Category) error {
+	current, err := s.GetCategory(id)
+	if err != nil {
+		return err
+	}
+	if current.Name == category.Name {
+		*category = *current
+		return nil
+	}
+	if _, err := s.getCategory(Category{Name: category.Name}); err != ErrCategoryNotFound {
+		if err == nil {
+			return ErrCategoryExists
+		}
+		return err
+	}
+
+	err = s.renameReferenced("category_name", current.Name, category.Name, func(tx *gorm.DB) error {
+		return tx.Model(current).Update("name", category.Name).Error
+	})
+	if err != nil {
+		return err
+	}
+	return storage(s.db.First(category, current.ID).Error)
+}
+
+// DeleteCategory delete a category using its ID, refused while articles still reference it
+func (s *GormStore) DeleteCategory(id int) error {
+	category, err := s.GetCategory(id)
+	if err != nil {
+		return err
+	}
+	return s.deleteReferenced(category, "category_name", category.Name, ErrCategoryInUse)
+}
+
+// SerializeCategory convert request into Category object
+func SerializeCategory(r io.ReadCloser) (*Category, error) {
+	category := &Category{}
+	if err := json.NewDecoder(r).Decode(category); err != nil {
+		return nil, fmt.Errorf("unable to decode json request body: %v", err)
+	}
+	defer r.Close()
+	return category, nil
 }
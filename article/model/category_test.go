@@ -0,0 +1,126 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+	"testing"
+)
+
+// Create Category
+func TestCreateCategory(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	tests := []struct {
+		name    string
+		args    Category
+		wantErr bool
+	}{
+		{"case 01", Category{Name: "sport"}, false},
+		{"case 02", Category{Name: "sport"}, true},
+		{"case 03", Category{Name: "news"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.CreateCategory(&tt.args)
+			if err != nil && !tt.wantErr {
+				t.Errorf("unable to create category:%v", err)
+			}
+			if err == nil && tt.wantErr {
+				t.Errorf("expected error creating %q", tt.args.Name)
+			}
+		})
+	}
+}
+
+// Rename Category
+func TestUpdateCategory(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := Article{Title: "Money", Body: "Money is good", CategoryName: "social", PublisherName: "femonofsky"}
+	if err := store.CreateArticle(&article); err != nil {
+		t.Fatalf("unable to create new article %v", err)
+	}
+	if err := store.CreateCategory(&Category{Name: "news"}); err != nil {
+		t.Fatalf("unable to create category %v", err)
+	}
+	social, err := store.getCategory(Category{Name: "social"})
+	if err != nil {
+		t.Fatalf("category was not created from article: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		id      uint
+		args    Category
+		wantErr bool
+	}{
+		{"case 01", social.ID, Category{Name: "society"}, false},
+		{"case 02", social.ID, Category{Name: "news"}, true},
+		{"case 03", 990, Category{Name: "sport"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.UpdateCategory(int(tt.id), &tt.args)
+			if err != nil && !tt.wantErr {
+				t.Errorf("unable to update category:%v", err)
+			}
+			if err == nil && tt.wantErr {
+				t.Errorf("expected error renaming to %q", tt.args.Name)
+			}
+		})
+	}
+
+	got, err := store.GetArticle(int(article.ID))
+	if err != nil {
+		t.Fatalf("unable to get article %v", err)
+	}
+	if got.CategoryName != "society" {
+		t.Errorf("article category was not renamed got: %v want: %v", got.CategoryName, "society")
+	}
+	// the rename is a new version of the article, its old ETag no longer matches
+	if got.Version != 2 {
+		t.Errorf("article version got: %v want: %v", got.Version, 2)
+	}
+}
+
+// Delete Category
+func TestDeleteCategory(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := Article{Title: "Money", Body: "Money is good", CategoryName: "social", PublisherName: "femonofsky"}
+	if err := store.CreateArticle(&article); err != nil {
+		t.Fatalf("unable to create new article %v", err)
+	}
+	empty := Category{Name: "news"}
+	if err := store.CreateCategory(&empty); err != nil {
+		t.Fatalf("unable to create category %v", err)
+	}
+	social, _ := store.getCategory(Category{Name: "social"})
+
+	tests := []struct {
+		name string
+		id   uint
+		want error
+	}{
+		{"case 01", social.ID, ErrCategoryInUse},
+		{"case 02", empty.ID, nil},
+		{"case 03", empty.ID, ErrCategoryNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.DeleteCategory(int(tt.id)); err != tt.want {
+				t.Errorf("DeleteCategory() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// a deleted name can be used again
+	if err := store.CreateCategory(&Category{Model: gorm.Model{}, Name: "news"}); err != nil {
+		t.Errorf("unable to recreate deleted category %v", err)
+	}
+}
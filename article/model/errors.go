@@ -0,0 +1,136 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator"
+	"reflect"
+	"strings"
+)
+
+// The kinds of error returned by the model, check them with errors.Is
+var (
+	// ErrNotFound the record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict the change clashes with stored data, e.g a duplicate name
+	ErrConflict = errors.New("conflict")
+	// ErrValidation the fields do not meet their requirements
+	ErrValidation = errors.New("validation failed")
+	// ErrStorage the database failed, the cause is kept for logs but not shown to clients
+	ErrStorage = errors.New("storage failure")
+	// ErrCanceled the context of the store ended first, e.g the request timed out, and nothing was changed
+	ErrCanceled = errors.New("canceled")
+)
+
+// kindError is a model error with its own message belonging to one of the kinds
+type kindError struct {
+	kind    error
+	message string
+}
+
+// newError returns an error with message which errors.Is matches to kind
+func newError(kind error, message string) error {
+	return &kindError{kind: kind, message: message}
+}
+
+func (err *kindError) Error() string {
+	return err.message
+}
+
+// Is reports whether target is the kind of the error
+func (err *kindError) Is(target error) bool {
+	return target == err.kind
+}
+
+// StorageError wraps a database error so only ErrStorage is shown to clients
+type StorageError struct {
+	Err error
+}
+
+func (err *StorageError) Error() string {
+	return ErrStorage.Error()
+}
+
+// Unwrap returns the database error for logging
+func (err *StorageError) Unwrap() error {
+	return err.Err
+}
+
+// Is reports whether target is ErrStorage
+func (err *StorageError) Is(target error) bool {
+	return target == ErrStorage
+}
+
+// canceled returns the ErrCanceled error of the error of an ended context
+func canceled(err error) error {
+	return newError(ErrCanceled, fmt.Sprintf("stopped before completing, nothing was changed: %v", err))
+}
+
+// storage classifies an error of the database, errors that already have a kind are returned as they are
+func storage(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrValidation, ErrStorage, ErrCanceled, ErrArticleChanged} {
+		if errors.Is(err, kind) {
+			return err
+		}
+	}
+	return &StorageError{Err: err}
+}
+
+// FieldError describes one field that did not meet a validation rule
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+// ValidationError is returned when fields of a model do not meet their requirements
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Is reports whether target is ErrValidation
+func (err *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Error lists the fields and the rule each one failed
+func (err *ValidationError) Error() string {
+	failed := make([]string, 0, len(err.Fields))
+	for _, field := range err.Fields {
+		failed = append(failed, fmt.Sprintf("%s (%s)", field.Field, field.Rule))
+	}
+	return "validation failed on " + strings.Join(failed, ", ")
+}
+
+// validate is shared by the models, it caches the struct rules and reports fields by their json name
+var validate = newValidator()
+
+// newValidator returns a validator naming fields after their json tag
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// validateStruct checks the validate tags of a model and returns a *ValidationError when they fail
+func validateStruct(value interface{}) error {
+	err := validate.Struct(value)
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, FieldError{Field: fe.Field(), Rule: fe.Tag(), Param: fe.Param()})
+	}
+	return &ValidationError{Fields: fields}
+}
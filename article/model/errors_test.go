@@ -0,0 +1,114 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// Validation errors name fields after their json tag
+func TestValidationError(t *testing.T) {
+	tests := []struct {
+		name string
+		args interface{ Validate() error }
+		want []FieldError
+	}{
+		{"case 01", &Article{Title: "Money", Body: "Money is good"}, []FieldError{
+			{Field: "category", Rule: "required"}, {Field: "publisher", Rule: "required"}}},
+		{"case 02", &Category{}, []FieldError{{Field: "name", Rule: "required"}}},
+		{"case 03", &Publisher{Name: "tunde", Website: "tunde", ContactEmail: "tunde"}, []FieldError{
+			{Field: "website", Rule: "url"}, {Field: "contact_email", Rule: "email"}}},
+		{"case 04", &Article{Title: "Money", Body: "Money is good", CategoryName: "social", PublisherName: "tunde"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("unable to validate data:%v", err)
+				}
+				return
+			}
+			verr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("Validate() = %#v, want *ValidationError", err)
+			}
+			if !reflect.DeepEqual(verr.Fields, tt.want) {
+				t.Errorf("Validate() fields = %v, want %v", verr.Fields, tt.want)
+			}
+		})
+	}
+}
+
+// A bad published_at is reported as a field error
+func TestSerializeDateValidationError(t *testing.T) {
+	body := ioutil.NopCloser(bytes.NewBufferString(`{"title": "Money", "published_at": "yesterday"}`))
+	_, err := Serialize(body)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Serialize() = %#v, want *ValidationError", err)
+	}
+	want := []FieldError{{Field: "published_at", Rule: "datetime", Param: DateTimeLayout}}
+	if !reflect.DeepEqual(verr.Fields, want) {
+		t.Errorf("Serialize() fields = %v, want %v", verr.Fields, want)
+	}
+}
+
+// Model errors match their kind
+func TestErrorKinds(t *testing.T) {
+	dbErr := fmt.Errorf(`pq: relation "articles" does not exist`)
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"case 01", ErrArticleNotFound, ErrNotFound},
+		{"case 02", ErrCategoryNotFound, ErrNotFound},
+		{"case 03", ErrPublisherNotFound, ErrNotFound},
+		{"case 04", ErrTitleExists, ErrConflict},
+		{"case 05", ErrCategoryExists, ErrConflict},
+		{"case 06", ErrPublisherInUse, ErrConflict},
+		{"case 07", &ValidationError{}, ErrValidation},
+		{"case 08", storage(dbErr), ErrStorage},
+		{"case 09", storage(ErrTitleExists), ErrConflict},
+		{"case 10", storage(ErrArticleChanged), ErrArticleChanged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, tt.kind) {
+				t.Errorf("errors.Is(%v, %v) = false", tt.err, tt.kind)
+			}
+		})
+	}
+
+	err := storage(dbErr)
+	if err.Error() != ErrStorage.Error() || errors.Unwrap(err) != dbErr {
+		t.Errorf("storage() = %v, should hide %v", err, dbErr)
+	}
+	if storage(nil) != nil {
+		t.Errorf("storage(nil) should be nil")
+	}
+}
+
+// Duplicates are reported as conflicts
+func TestCreateArticleConflict(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := Article{Title: "Money", Body: "Money is good", CategoryName: "social", PublisherName: "femonofsky"}
+	if err := store.CreateArticle(&article); err != nil {
+		t.Fatalf("unable to create new article %v", err)
+	}
+	duplicate := Article{Title: "Money", Body: "Money is bad", CategoryName: "social", PublisherName: "femonofsky"}
+	if err := store.CreateArticle(&duplicate); err != ErrTitleExists {
+		t.Errorf("CreateArticle() = %v, want %v", err, ErrTitleExists)
+	}
+	if _, err := store.GetArticle(990); err != ErrArticleNotFound {
+		t.Errorf("GetArticle() = %v, want %v", err, ErrArticleNotFound)
+	}
+}
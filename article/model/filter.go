@@ -0,0 +1,161 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchTerms caps the number of words of a search used in the query
+const maxSearchTerms = 10
+
+// searchWeights ranks a search term found in a column, a title match counts more than a body match.
+// field reads the column from an article outside of the database
+var searchWeights = []struct {
+	column string
+	weight int
+	field  func(*Article) string
+}{
+	{"title", 2, func(article *Article) string { return article.Title }},
+	{"body", 1, func(article *Article) string { return article.Body }},
+}
+
+// ArticleFilter selects articles, empty fields do not filter
+type ArticleFilter struct {
+	// Search holds words that must all appear in the title or body of an article
+	Search        string
+	CategoryName  string
+	PublisherName string
+	CreatedAt     TimeRange
+	PublishedAt   TimeRange
+}
+
+// TimeRange bounds a time column.
+// From and To are inclusive, After and Before are exclusive, zero times are ignored
+type TimeRange struct {
+	From   time.Time
+	To     time.Time
+	After  time.Time
+	Before time.Time
+}
+
+// Exactly returns a TimeRange matching only the given time
+func Exactly(t time.Time) TimeRange {
+	return TimeRange{From: t, To: t}
+}
+
+// apply adds the conditions of the filter to the query
+func (filter ArticleFilter) apply(query *gorm.DB) *gorm.DB {
+	for _, term := range filter.searchTerms() {
+		var conditions []string
+		var args []interface{}
+		for _, w := range searchWeights {
+			conditions = append(conditions, "LOWER("+w.column+") LIKE ? ESCAPE '!'")
+			args = append(args, term)
+		}
+		query = query.Where(strings.Join(conditions, " OR "), args...)
+	}
+	if filter.CategoryName != "" {
+		query = query.Where("category_name = ?", filter.CategoryName)
+	}
+	if filter.PublisherName != "" {
+		query = query.Where("publisher_name = ?", filter.PublisherName)
+	}
+	query = filter.CreatedAt.apply(query, "created_at")
+	query = filter.PublishedAt.apply(query, "published_at")
+	return query
+}
+
+// apply adds the bounds of the range on column to the query
+func (r TimeRange) apply(query *gorm.DB, column string) *gorm.DB {
+	if !r.From.IsZero() {
+		query = query.Where(column+" >= ?", r.From)
+	}
+	if !r.To.IsZero() {
+		query = query.Where(column+" <= ?", r.To)
+	}
+	if !r.After.IsZero() {
+		query = query.Where(column+" > ?", r.After)
+	}
+	if !r.Before.IsZero() {
+		query = query.Where(column+" < ?", r.Before)
+	}
+	return query
+}
+
+// searchWords splits the search into the lower case words an article must contain
+func (filter ArticleFilter) searchWords() []string {
+	words := strings.Fields(strings.ToLower(filter.Search))
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+	return words
+}
+
+// searchTerms splits the search into lower case LIKE patterns.
+// LIKE is used rather than a full-text index so the search behaves the same on postgres, mysql and sqlite
+func (filter ArticleFilter) searchTerms() []string {
+	words := filter.searchWords()
+	escape := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, "%"+escape.Replace(word)+"%")
+	}
+	return terms
+}
+
+// rank returns an order expression putting the best search matches first, nil without a search
+func (filter ArticleFilter) rank() *gorm.SqlExpr {
+	terms := filter.searchTerms()
+	if len(terms) == 0 {
+		return nil
+	}
+	var scores []string
+	var args []interface{}
+	for _, term := range terms {
+		for _, w := range searchWeights {
+			scores = append(scores, "CASE WHEN LOWER("+w.column+") LIKE ? ESCAPE '!' THEN "+strconv.Itoa(w.weight)+" ELSE 0 END")
+			args = append(args, term)
+		}
+	}
+	return gorm.Expr("("+strings.Join(scores, " + ")+") DESC", args...)
+}
+
+// matches reports whether the article meets the conditions apply adds to a query
+func (filter ArticleFilter) matches(article *Article) bool {
+	for _, word := range filter.searchWords() {
+		found := false
+		for _, w := range searchWeights {
+			found = found || strings.Contains(strings.ToLower(w.field(article)), word)
+		}
+		if !found {
+			return false
+		}
+	}
+	return (filter.CategoryName == "" || article.CategoryName == filter.CategoryName) &&
+		(filter.PublisherName == "" || article.PublisherName == filter.PublisherName) &&
+		filter.CreatedAt.contains(article.CreatedAt) &&
+		filter.PublishedAt.contains(article.PublishedAt)
+}
+
+// score is the search rank of the article, the weight of each column a search word is found in
+func (filter ArticleFilter) score(article *Article) int {
+	score := 0
+	for _, word := range filter.searchWords() {
+		for _, w := range searchWeights {
+			if strings.Contains(strings.ToLower(w.field(article)), word) {
+				score += w.weight
+			}
+		}
+	}
+	return score
+}
+
+// contains reports whether t is within the bounds of the range
+func (r TimeRange) contains(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) &&
+		(r.To.IsZero() || !t.After(r.To)) &&
+		(r.After.IsZero() || t.After(r.After)) &&
+		(r.Before.IsZero() || t.Before(r.Before))
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Filter Articles by date ranges
+func TestArticleFilterTimeRange(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	day := func(d int) time.Time {
+		return time.Date(2020, time.February, d, 0, 0, 0, 0, time.UTC)
+	}
+	for d := 1; d <= 4; d++ {
+		article := Article{Title: day(d).Format("Jan 2"), Body: "Money is good", CategoryName: "social",
+			PublisherName: "femonofsky", PublishedAt: day(d)}
+		if err := store.CreateArticle(&article); err != nil {
+			t.Fatalf("unable to create new article %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		arg  ArticleFilter
+		want []string
+	}{
+		{"case 01", ArticleFilter{PublishedAt: Exactly(day(2))}, []string{"Feb 2"}},
+		{"case 02", ArticleFilter{PublishedAt: TimeRange{From: day(2), To: day(3)}}, []string{"Feb 2", "Feb 3"}},
+		{"case 03", ArticleFilter{PublishedAt: TimeRange{After: day(2), Before: day(4)}}, []string{"Feb 3"}},
+		{"case 04", ArticleFilter{PublishedAt: TimeRange{From: day(3)}}, []string{"Feb 3", "Feb 4"}},
+		{"case 05", ArticleFilter{PublishedAt: TimeRange{Before: day(2)}}, []string{"Feb 1"}},
+		{"case 06", ArticleFilter{CreatedAt: TimeRange{Before: day(2)}}, []string{}},
+		{"case 07", ArticleFilter{CategoryName: "social", PublishedAt: TimeRange{To: day(1)}}, []string{"Feb 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getArticles(store, tt.arg)
+			if err != nil {
+				t.Fatalf("unable to filter articles:%v", err)
+			}
+			titles := []string{}
+			for _, article := range got {
+				titles = append(titles, article.Title)
+			}
+			if !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("filtered titles = %v, want %v", titles, tt.want)
+			}
+		})
+	}
+}
+
+// Search Articles by title and body
+func TestArticleFilterSearch(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	articles := Articles{
+		&Article{Title: "Gardening basics", Body: "Start with money plants", CategoryName: "home", PublisherName: "tunde"},
+		&Article{Title: "Money in the bank", Body: "Saving money", CategoryName: "finance", PublisherName: "tunde"},
+		&Article{Title: "Love of Money", Body: "A story", CategoryName: "finance", PublisherName: "femonofsky"},
+		&Article{Title: "100% returns", Body: "Too good to be true", CategoryName: "finance", PublisherName: "tunde"},
+	}
+	for _, article := range articles {
+		if err := store.CreateArticle(article); err != nil {
+			t.Fatalf("unable to create new article %v", err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		arg  ArticleFilter
+		want []string
+	}{
+		{"case 01", ArticleFilter{Search: "money"}, []string{"Money in the bank", "Love of Money", "Gardening basics"}},
+		{"case 02", ArticleFilter{Search: "MONEY bank"}, []string{"Money in the bank"}},
+		{"case 03", ArticleFilter{Search: "money", CategoryName: "finance"}, []string{"Money in the bank", "Love of Money"}},
+		{"case 04", ArticleFilter{Search: "money", PublisherName: "tunde"}, []string{"Money in the bank", "Gardening basics"}},
+		{"case 05", ArticleFilter{Search: "100%"}, []string{"100% returns"}},
+		{"case 06", ArticleFilter{Search: "%"}, []string{"100% returns"}},
+		{"case 07", ArticleFilter{Search: "gold"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getArticles(store, tt.arg)
+			if err != nil {
+				t.Fatalf("unable to search articles:%v", err)
+			}
+			titles := []string{}
+			for _, article := range got {
+				titles = append(titles, article.Title)
+			}
+			if !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("search titles = %v, want %v", titles, tt.want)
+			}
+		})
+	}
+
+	sort, _ := ParseArticleSort("title")
+	got, total, err := store.GetArticlesPage(ArticleFilter{Search: "money"}, Page{Limit: 2, Sort: sort})
+	if err != nil || total != 3 || len(got) != 2 || got[0].Title != "Gardening basics" {
+		t.Errorf("sorted search page = %v of %v, %v", got, total, err)
+	}
+}
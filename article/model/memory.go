@@ -0,0 +1,623 @@
+package model
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryDriver is the config.DB.Driver value that keeps the records in a MemoryStore instead of a database
+const MemoryDriver = "memory"
+
+// MemoryStore is a Store keeping the records in the memory of the process, they are lost when it stops.
+// It answers like a GormStore of a migrated database and is safe for concurrent use
+type MemoryStore struct {
+	*memoryRecords
+	// ctx is the context set by WithContext, nil when the store is not bound
+	ctx context.Context
+}
+
+// memoryRecords are the records of a MemoryStore, shared with the copies bound by WithContext
+type memoryRecords struct {
+	mu         sync.RWMutex
+	articles   map[uint]*Article
+	categories map[uint]*Category
+	publishers map[uint]*Publisher
+	// lastID is the last ID given to a record of each table, IDs are never reused
+	lastID struct{ article, category, publisher uint }
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{memoryRecords: &memoryRecords{
+		articles:   map[uint]*Article{},
+		categories: map[uint]*Category{},
+		publishers: map[uint]*Publisher{},
+	}}
+}
+
+// WithContext returns the store bound to ctx, a change is checked against ctx once it holds the records
+func (s *MemoryStore) WithContext(ctx context.Context) Store {
+	return &MemoryStore{memoryRecords: s.memoryRecords, ctx: ctx}
+}
+
+// done returns ErrCanceled once the context of the store ended
+func (s *MemoryStore) done() error {
+	if s.ctx == nil || s.ctx.Err() == nil {
+		return nil
+	}
+	return canceled(s.ctx.Err())
+}
+
+// Ping checks that the storage answers, memory always does
+func (s *MemoryStore) Ping() error {
+	return nil
+}
+
+// Migrated checks that the storage is ready for the models, memory needs no migration
+func (s *MemoryStore) Migrated() error {
+	return nil
+}
+
+// articleColumns compares two articles on each column a listing can be sorted by
+var articleColumns = map[string]func(a, b *Article) int{
+	"id":             func(a, b *Article) int { return compareUint(a.ID, b.ID) },
+	"title":          func(a, b *Article) int { return strings.Compare(a.Title, b.Title) },
+	"category_name":  func(a, b *Article) int { return strings.Compare(a.CategoryName, b.CategoryName) },
+	"publisher_name": func(a, b *Article) int { return strings.Compare(a.PublisherName, b.PublisherName) },
+	"created_at":     func(a, b *Article) int { return compareTime(a.CreatedAt, b.CreatedAt) },
+	"published_at":   func(a, b *Article) int { return compareTime(a.PublishedAt, b.PublishedAt) },
+}
+
+func compareUint(a, b uint) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// find returns copies of the articles matching the filter ordered like order does:
+// by the sort fields, then the search rank, then id
+func (s *MemoryStore) find(filter ArticleFilter, sortBy []SortField) Articles {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	articles := Articles{}
+	scores := map[*Article]int{}
+	for _, stored := range s.articles {
+		if filter.matches(stored) {
+			article := *stored
+			articles = append(articles, &article)
+			scores[&article] = filter.score(stored)
+		}
+	}
+	sort.Slice(articles, func(i, j int) bool {
+		a, b := articles[i], articles[j]
+		for _, field := range sortBy {
+			compare, ok := articleColumns[field.Column]
+			if !ok {
+				continue
+			}
+			c := compare(a, b)
+			if field.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return a.ID < b.ID
+	})
+	return articles
+}
+
+// GetArticlesPage returns one page of the articles matching the filter
+// together with the total number of matching articles
+func (s *MemoryStore) GetArticlesPage(filter ArticleFilter, page Page) (Articles, int, error) {
+	if err := s.done(); err != nil {
+		return nil, 0, err
+	}
+	articles := s.find(filter, page.Sort)
+	total := len(articles)
+	if page.Offset > 0 {
+		if page.Offset > len(articles) {
+			page.Offset = len(articles)
+		}
+		articles = articles[page.Offset:]
+	}
+	if page.Limit > 0 && page.Limit < len(articles) {
+		articles = articles[:page.Limit]
+	}
+	return articles, total, nil
+}
+
+// EachArticle calls fn with every article matching the filter, in the order of GetArticlesPage without a sort.
+// fn is called without holding the store so it may use it,
+// the first error of fn stops the iteration and is returned
+func (s *MemoryStore) EachArticle(filter ArticleFilter, fn func(*Article) error) error {
+	for _, article := range s.find(filter, nil) {
+		if err := s.done(); err != nil {
+			return err
+		}
+		if err := fn(article); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetArticle get article by ID
+func (s *MemoryStore) GetArticle(id int) (*Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	stored, err := s.article(id)
+	if err != nil {
+		return nil, err
+	}
+	article := *stored
+	return &article, nil
+}
+
+// article returns the stored article of the ID, the caller holds the lock
+func (s *MemoryStore) article(id int) (*Article, error) {
+	if id <= 0 {
+		return nil, ErrArticleNotFound
+	}
+	article, ok := s.articles[uint(id)]
+	if !ok {
+		return nil, ErrArticleNotFound
+	}
+	return article, nil
+}
+
+// titleTaken returns ErrTitleExists when an article other than id has the title, the caller holds the lock
+func (s *MemoryStore) titleTaken(title string, id uint) error {
+	for _, other := range s.articles {
+		if other.Title == title && other.ID != id {
+			return ErrTitleExists
+		}
+	}
+	return nil
+}
+
+// CreateArticle create new  Article
+func (s *MemoryStore) CreateArticle(article *Article) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return err
+	}
+	if err := s.titleTaken(article.Title, 0); err != nil {
+		return err
+	}
+	s.create(article)
+	return nil
+}
+
+// create stores the article with the next ID as gorm inserts it, the caller holds the lock.
+// Its category and publisher are created when they do not exist, like BeforeSave does
+func (s *MemoryStore) create(article *Article) {
+	now := time.Now()
+	s.lastID.article++
+	article.ID = s.lastID.article
+	article.Version = 1
+	if article.CreatedAt.IsZero() {
+		article.CreatedAt = now
+	}
+	article.UpdatedAt = now
+	s.reference(article)
+	stored := *article
+	stored.Category, stored.Publisher = Category{}, Publisher{}
+	s.articles[stored.ID] = &stored
+}
+
+// reference sets the category and publisher of the article, creating those that do not exist.
+// The caller holds the lock
+func (s *MemoryStore) reference(article *Article) {
+	category, err := s.categoryByName(article.CategoryName)
+	if err != nil {
+		category = &Category{Name: article.CategoryName}
+		s.createCategory(category)
+	}
+	article.Category = *category
+	publisher, err := s.publisherByName(article.PublisherName)
+	if err != nil {
+		publisher = &Publisher{Name: article.PublisherName}
+		s.createPublisher(publisher)
+	}
+	article.Publisher = *publisher
+}
+
+// checkVersion returns ErrArticleChanged unless the stored article is at version, zero skips the check
+func checkVersion(stored *Article, version uint) error {
+	if version != 0 && stored.Version != version {
+		return ErrArticleChanged
+	}
+	return nil
+}
+
+// UpdateArticle replaces the Article with the given ID, every field is written including zero values.
+// version is the stored version the caller expects, zero skips the check.
+// article is reloaded with the stored values
+func (s *MemoryStore) UpdateArticle(id int, article *Article, version uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return err
+	}
+	stored, err := s.article(id)
+	if err != nil {
+		return err
+	}
+	if err := s.titleTaken(article.Title, stored.ID); err != nil {
+		return err
+	}
+	if err := checkVersion(stored, version); err != nil {
+		return err
+	}
+
+	updated := *stored
+	updated.Title = article.Title
+	updated.Body = article.Body
+	updated.CategoryName = article.CategoryName
+	updated.PublisherName = article.PublisherName
+	updated.PublishedAt = article.PublishedAt
+	updated.Version = stored.Version + 1
+	updated.UpdatedAt = time.Now()
+	s.reference(&updated)
+	updated.Category, updated.Publisher = Category{}, Publisher{}
+	s.articles[updated.ID] = &updated
+
+	*article = updated
+	return nil
+}
+
+// DeleteArticle Article using article id,
+// version is the stored version the caller expects, zero skips the check
+func (s *MemoryStore) DeleteArticle(id int, version uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return err
+	}
+	stored, err := s.article(id)
+	if err != nil {
+		return err
+	}
+	if err := checkVersion(stored, version); err != nil {
+		return err
+	}
+	delete(s.articles, stored.ID)
+	return nil
+}
+
+// ImportArticles creates a batch of articles, the returned errors line up with articles and are nil for created ones.
+// Each article is validated and its title checked against the rest of the batch and the stored articles.
+// When atomic is set nothing is stored unless every article can be, otherwise every valid article is stored
+func (s *MemoryStore) ImportArticles(articles Articles, atomic bool) []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return cancelImport(articles, err)
+	}
+	errs, _ := checkBatch(articles)
+	for i, article := range articles {
+		if errs[i] == nil && s.titleTaken(article.Title, 0) != nil {
+			errs[i] = ErrTitleExists
+		}
+	}
+	if atomic {
+		for _, err := range errs {
+			if err != nil {
+				return abortImport(articles, errs)
+			}
+		}
+	}
+	// the store is held since the check so every checked article can be created
+	for i, article := range articles {
+		if errs[i] == nil {
+			s.create(article)
+		}
+	}
+	return errs
+}
+
+// GetCategories returns all categories ordered by name
+func (s *MemoryStore) GetCategories() (Categories, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	categories := Categories{}
+	for _, stored := range s.categories {
+		category := *stored
+		categories = append(categories, &category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+// GetCategory get category by ID
+func (s *MemoryStore) GetCategory(id int) (*Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	stored, err := s.category(id)
+	if err != nil {
+		return nil, err
+	}
+	category := *stored
+	return &category, nil
+}
+
+// category returns the stored category of the ID, the caller holds the lock
+func (s *MemoryStore) category(id int) (*Category, error) {
+	if id <= 0 {
+		return nil, ErrCategoryNotFound
+	}
+	category, ok := s.categories[uint(id)]
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+// categoryByName returns the stored category of the name, the caller holds the lock
+func (s *MemoryStore) categoryByName(name string) (*Category, error) {
+	for _, category := range s.categories {
+		if category.Name == name {
+			return category, nil
+		}
+	}
+	return nil, ErrCategoryNotFound
+}
+
+// CreateCategory create new Category
+func (s *MemoryStore) CreateCategory(category *Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return err
+	}
+	if _, err := s.categoryByName(category.Name); err == nil {
+		return ErrCategoryExists
+	}
+	s.createCategory(category)
+	return nil
+}
+
+// createCategory stores the category with the next ID, the caller holds the lock
+func (s *MemoryStore) createCategory(category *Category) {
+	now := time.Now()
+	s.lastID.category++
+	category.ID = s.lastID.category
+	category.CreatedAt, category.UpdatedAt = now, now
+	stored := *category
+	s.categories[stored.ID] = &stored
+}
+
+// UpdateCategory renames the category with the given ID, its articles follow the new name and move to their next version
+func (s *MemoryStore) UpdateCategory(id int, category *Category) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return err
+	}
+	current, err := s.category(id)
+	if err != nil {
+		return err
+	}
+	if current.Name == category.Name {
+		*category = *current
+		return nil
+	}
+	if _, err := s.categoryByName(category.Name); err == nil {
+		return ErrCategoryExists
+	}
+
+	for _, article := range s.articles {
+		if article.CategoryName == current.Name {
+			article.CategoryName = category.Name
+			article.Version++
+		}
+	}
+	updated := *current
+	updated.Name = category.Name
+	updated.UpdatedAt = time.Now()
+	s.categories[updated.ID] = &updated
+	*category = updated
+	return nil
+}
+
+// DeleteCategory delete a category using its ID, refused while articles still reference it
+func (s *MemoryStore) DeleteCategory(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return err
+	}
+	category, err := s.category(id)
+	if err != nil {
+		return err
+	}
+	for _, article := range s.articles {
+		if article.CategoryName == category.Name {
+			return ErrCategoryInUse
+		}
+	}
+	delete(s.categories, category.ID)
+	return nil
+}
+
+// GetPublishers returns all publishers ordered by name
+func (s *MemoryStore) GetPublishers() (Publishers, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	publishers := Publishers{}
+	for _, stored := range s.publishers {
+		publisher := *stored
+		publishers = append(publishers, &publisher)
+	}
+	sort.Slice(publishers, func(i, j int) bool { return publishers[i].Name < publishers[j].Name })
+	return publishers, nil
+}
+
+// GetPublisher get publisher by ID
+func (s *MemoryStore) GetPublisher(id int) (*Publisher, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	stored, err := s.publisher(id)
+	if err != nil {
+		return nil, err
+	}
+	publisher := *stored
+	return &publisher, nil
+}
+
+// GetPublisherByName get publisher by name
+func (s *MemoryStore) GetPublisherByName(name string) (*Publisher, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	stored, err := s.publisherByName(name)
+	if err != nil {
+		return nil, err
+	}
+	publisher := *stored
+	return &publisher, nil
+}
+
+// publisher returns the stored publisher of the ID, the caller holds the lock
+func (s *MemoryStore) publisher(id int) (*Publisher, error) {
+	if id <= 0 {
+		return nil, ErrPublisherNotFound
+	}
+	publisher, ok := s.publishers[uint(id)]
+	if !ok {
+		return nil, ErrPublisherNotFound
+	}
+	return publisher, nil
+}
+
+// publisherByName returns the stored publisher of the name, the caller holds the lock
+func (s *MemoryStore) publisherByName(name string) (*Publisher, error) {
+	for _, publisher := range s.publishers {
+		if publisher.Name == name {
+			return publisher, nil
+		}
+	}
+	return nil, ErrPublisherNotFound
+}
+
+// CreatePublisher create new Publisher
+func (s *MemoryStore) CreatePublisher(publisher *Publisher) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return err
+	}
+	if _, err := s.publisherByName(publisher.Name); err == nil {
+		return ErrPublisherExists
+	}
+	s.createPublisher(publisher)
+	return nil
+}
+
+// createPublisher stores the publisher with the next ID, the caller holds the lock
+func (s *MemoryStore) createPublisher(publisher *Publisher) {
+	now := time.Now()
+	s.lastID.publisher++
+	publisher.ID = s.lastID.publisher
+	publisher.CreatedAt, publisher.UpdatedAt = now, now
+	stored := *publisher
+	s.publishers[stored.ID] = &stored
+}
+
+// UpdatePublisher replaces the name and profile of the publisher with the given ID,
+// its articles follow a new name and move to their next version
+func (s *MemoryStore) UpdatePublisher(id int, publisher *Publisher) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return err
+	}
+	current, err := s.publisher(id)
+	if err != nil {
+		return err
+	}
+	if current.Name != publisher.Name {
+		if _, err := s.publisherByName(publisher.Name); err == nil {
+			return ErrPublisherExists
+		}
+		for _, article := range s.articles {
+			if article.PublisherName == current.Name {
+				article.PublisherName = publisher.Name
+				article.Version++
+			}
+		}
+	}
+
+	updated := *current
+	updated.Name = publisher.Name
+	updated.DisplayName = publisher.DisplayName
+	updated.Bio = publisher.Bio
+	updated.Website = publisher.Website
+	updated.ContactEmail = publisher.ContactEmail
+	updated.UpdatedAt = time.Now()
+	s.publishers[updated.ID] = &updated
+	*publisher = updated
+	return nil
+}
+
+// DeletePublisher delete a publisher using its ID, refused while articles still reference it
+func (s *MemoryStore) DeletePublisher(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.done(); err != nil {
+		return err
+	}
+	publisher, err := s.publisher(id)
+	if err != nil {
+		return err
+	}
+	for _, article := range s.articles {
+		if article.PublisherName == publisher.Name {
+			return ErrPublisherInUse
+		}
+	}
+	delete(s.publishers, publisher.ID)
+	return nil
+}
+
+var _ Store = (*MemoryStore)(nil)
@@ -0,0 +1,203 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The memory store answers every step like a migrated sqlite database
+func TestMemoryStore(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer db.Close()
+	// every connection to :memory: opens a database of its own
+	db.DB().SetMaxOpenConns(1)
+	db.AutoMigrate(&Article{}, &Category{}, &Publisher{})
+
+	published := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	article := func(title, body, category, publisher string) *Article {
+		return &Article{Title: title, Body: body, CategoryName: category, PublisherName: publisher, PublishedAt: published}
+	}
+	titles := func(articles Articles) []string {
+		var titles []string
+		for _, article := range articles {
+			titles = append(titles, article.Title+"@"+article.CategoryName+"/"+article.PublisherName)
+		}
+		return titles
+	}
+
+	tests := []struct {
+		name string
+		step func(s Store) []interface{}
+	}{
+		{"case 01", func(s Store) []interface{} {
+			a := article("Money", "Money is good", "social", "tunde")
+			err := s.CreateArticle(a)
+			return []interface{}{err, a.ID, a.Version, a.Category.Name, a.Publisher.Name}
+		}},
+		{"case 02", func(s Store) []interface{} {
+			return []interface{}{s.CreateArticle(article("Money", "Money is bad", "social", "tunde"))}
+		}},
+		{"case 03", func(s Store) []interface{} {
+			a := article("Golang", "Go is good for money", "tech", "femonofsky")
+			err := s.CreateArticle(a)
+			return []interface{}{err, a.ID}
+		}},
+		{"case 04", func(s Store) []interface{} {
+			categories, err := s.GetCategories()
+			publishers, _ := s.GetPublishers()
+			return []interface{}{err, len(categories), categories[0].Name, categories[1].Name,
+				len(publishers), publishers[0].Name, publishers[1].Name}
+		}},
+		{"case 05", func(s Store) []interface{} {
+			articles, err := getArticles(s, ArticleFilter{Search: "MONEY good"})
+			return []interface{}{err, titles(articles)}
+		}},
+		{"case 06", func(s Store) []interface{} {
+			articles, err := getArticles(s, ArticleFilter{CategoryName: "tech", PublishedAt: Exactly(published)})
+			none, _ := getArticles(s, ArticleFilter{PublishedAt: TimeRange{After: published}})
+			return []interface{}{err, titles(articles), titles(none)}
+		}},
+		{"case 07", func(s Store) []interface{} {
+			articles, total, err := s.GetArticlesPage(ArticleFilter{}, Page{Limit: 1, Offset: 1, Sort: []SortField{{Column: "title", Desc: true}}})
+			return []interface{}{err, total, titles(articles)}
+		}},
+		{"case 08", func(s Store) []interface{} {
+			return []interface{}{s.UpdateArticle(1, article("Golang", "Taken", "social", "tunde"), 0)}
+		}},
+		{"case 09", func(s Store) []interface{} {
+			return []interface{}{s.UpdateArticle(1, article("Money", "Stale", "social", "tunde"), 2)}
+		}},
+		{"case 10", func(s Store) []interface{} {
+			a := article("Riches", "Money is great", "social", "bola")
+			err := s.UpdateArticle(1, a, 1)
+			publisher, perr := s.GetPublisherByName("bola")
+			return []interface{}{err, a.ID, a.Title, a.Version, perr, publisher.Name}
+		}},
+		{"case 11", func(s Store) []interface{} {
+			category := &Category{Name: "news"}
+			err := s.UpdateCategory(1, category)
+			articles, _ := getArticles(s, ArticleFilter{CategoryName: "news"})
+			return []interface{}{err, category.ID, category.Name, titles(articles), articles[0].Version, s.DeleteCategory(1)}
+		}},
+		{"case 12", func(s Store) []interface{} {
+			publisher := &Publisher{Name: "tunde"}
+			err := s.UpdatePublisher(3, publisher)
+			renamed := &Publisher{Name: "Bola", Bio: "Writes"}
+			rerr := s.UpdatePublisher(3, renamed)
+			article, _ := s.GetArticle(1)
+			return []interface{}{err, rerr, renamed.Bio, article.PublisherName, article.Version, s.DeletePublisher(1), s.DeletePublisher(3)}
+		}},
+		{"case 13", func(s Store) []interface{} {
+			errs := s.ImportArticles(Articles{
+				article("Love", "Love is good", "social", "tunde"),
+				article("Golang", "Again", "tech", "tunde"),
+			}, true)
+			articles, _ := getArticles(s, ArticleFilter{})
+			return []interface{}{errs, titles(articles)}
+		}},
+		{"case 14", func(s Store) []interface{} {
+			batch := Articles{
+				article("Love", "Love is good", "life", "tunde"),
+				article("Golang", "Again", "tech", "tunde"),
+				article("Love", "Love is bad", "life", "tunde"),
+				article("", "No title", "life", "tunde"),
+				nil,
+			}
+			errs := s.ImportArticles(batch, false)
+			categories, _ := s.GetCategories()
+			return []interface{}{fmt.Sprint(errs), batch[0].ID, batch[0].Version, len(categories)}
+		}},
+		{"case 15", func(s Store) []interface{} {
+			var seen []uint
+			err := s.EachArticle(ArticleFilter{Search: "good"}, func(article *Article) error {
+				seen = append(seen, article.ID)
+				return nil
+			})
+			return []interface{}{err, seen}
+		}},
+		{"case 16", func(s Store) []interface{} {
+			stale := s.DeleteArticle(1, 1)
+			err := s.DeleteArticle(1, 0)
+			_, gerr := s.GetArticle(1)
+			return []interface{}{stale, err, gerr, s.DeleteArticle(1, 0), s.DeleteCategory(1)}
+		}},
+		{"case 17", func(s Store) []interface{} {
+			_, cerr := s.GetCategory(99)
+			_, perr := s.GetPublisher(0)
+			category := &Category{Name: "tech"}
+			return []interface{}{cerr, perr, s.CreateCategory(category), s.CreatePublisher(&Publisher{Name: "tunde"})}
+		}},
+		{"case 18", func(s Store) []interface{} {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			bound := s.WithContext(ctx)
+			_, gerr := bound.GetArticle(2)
+			errs := bound.ImportArticles(Articles{article("Late", "Too late", "tech", "tunde")}, false)
+			cerr := bound.CreateArticle(article("Late", "Too late", "tech", "tunde"))
+			articles, _ := getArticles(s, ArticleFilter{Search: "late"})
+			return []interface{}{gerr, errs, cerr, errors.Is(cerr, ErrCanceled), bound.DeleteCategory(2), len(articles)}
+		}},
+	}
+
+	gormStore, memoryStore := NewGormStore(db), NewMemoryStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, got := tt.step(gormStore), tt.step(memoryStore)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("memory store = %v, gorm store = %v", got, want)
+			}
+		})
+	}
+}
+
+// Concurrent writers of the memory store keep titles unique and versions in order
+func TestMemoryStoreConcurrency(t *testing.T) {
+	s := NewMemoryStore()
+	const writers = 20
+
+	var wg sync.WaitGroup
+	created, updated := make(chan error, writers), make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			created <- s.CreateArticle(&Article{Title: "Race", Body: "Who wins", CategoryName: "social", PublisherName: "tunde"})
+			article := &Article{Title: "Race", Body: fmt.Sprint("Writer ", i), CategoryName: "social", PublisherName: "tunde"}
+			updated <- s.UpdateArticle(1, article, 1)
+			getArticles(s, ArticleFilter{Search: "writer"})
+		}(i)
+	}
+	wg.Wait()
+	close(created)
+	close(updated)
+
+	count := func(errs chan error, want error) (n int) {
+		for err := range errs {
+			if err == nil {
+				n++
+			} else if err != want {
+				t.Errorf("unexpected error %v", err)
+			}
+		}
+		return n
+	}
+	if n := count(created, ErrTitleExists); n != 1 {
+		t.Errorf("%d articles created, want 1", n)
+	}
+	if n := count(updated, ErrArticleChanged); n != 1 {
+		t.Errorf("%d updates of version 1, want 1", n)
+	}
+	categories, _ := s.GetCategories()
+	if article, err := s.GetArticle(1); err != nil || article.Version != 2 || len(categories) != 1 {
+		t.Errorf("GetArticle(1) = %+v, %v with %d categories, want version 2 with 1 category", article, err, len(categories))
+	}
+}
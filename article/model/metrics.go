@@ -0,0 +1,86 @@
+package model
+
+import (
+	"database/sql"
+	"github.com/femonofsky/articleMaker/article/metrics"
+	"github.com/jinzhu/gorm"
+	"sync"
+	"time"
+)
+
+var (
+	dbQueries = metrics.Default.NewCounterVec("db_queries_total",
+		"Database statements run by gorm, by operation and status.", "operation", "status")
+	dbQueryDuration = metrics.Default.NewHistogramVec("db_query_duration_seconds",
+		"Time gorm took to run a database statement, by operation.", metrics.DefaultBuckets, "operation")
+)
+
+func init() {
+	metrics.Default.NewGaugeFunc("db_open_connections", "Connections to the database, in use or idle.", func() float64 {
+		return float64(dbStats().OpenConnections)
+	})
+	metrics.Default.NewGaugeFunc("db_in_use_connections", "Connections to the database running a statement.", func() float64 {
+		return float64(dbStats().InUse)
+	})
+	metrics.Default.NewGaugeFunc("db_idle_connections", "Connections to the database waiting for a statement.", func() float64 {
+		return float64(dbStats().Idle)
+	})
+}
+
+// pools are the connection pools of the databases opened by New, the gauges add them up
+var pools struct {
+	sync.Mutex
+	dbs []*sql.DB
+}
+
+// dbStats returns the connection counts of every pool together
+func dbStats() (stats sql.DBStats) {
+	pools.Lock()
+	defer pools.Unlock()
+	for _, db := range pools.dbs {
+		pool := db.Stats()
+		stats.OpenConnections += pool.OpenConnections
+		stats.InUse += pool.InUse
+		stats.Idle += pool.Idle
+	}
+	return stats
+}
+
+// instrument adds the pool of db to the gauges and times the statements of each gorm operation of db,
+// the timer wraps the callback running the SQL
+func instrument(db *gorm.DB) {
+	pools.Lock()
+	pools.dbs = append(pools.dbs, db.DB())
+	pools.Unlock()
+
+	callbacks := db.Callback()
+	for _, op := range []struct {
+		name string
+		// processor returns a new processor each call, one keeps the position of the last callback registered
+		processor func() *gorm.CallbackProcessor
+		callback  string
+	}{
+		{"create", callbacks.Create, "gorm:create"},
+		{"query", callbacks.Query, "gorm:query"},
+		{"row_query", callbacks.RowQuery, "gorm:row_query"},
+		{"update", callbacks.Update, "gorm:update"},
+		{"delete", callbacks.Delete, "gorm:delete"},
+	} {
+		name := op.name
+		op.processor().Before(op.callback).Register("metrics:start_"+name, func(scope *gorm.Scope) {
+			scope.InstanceSet("metrics:start", time.Now())
+		})
+		op.processor().After(op.callback).Register("metrics:end_"+name, func(scope *gorm.Scope) {
+			start, ok := scope.InstanceGet("metrics:start")
+			if !ok {
+				return
+			}
+			status := "ok"
+			if err := scope.DB().Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+				status = "error"
+			}
+			dbQueries.Inc(name, status)
+			dbQueryDuration.Observe(time.Since(start.(time.Time)).Seconds(), name)
+		})
+	}
+}
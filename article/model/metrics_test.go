@@ -0,0 +1,66 @@
+package model
+
+import (
+	"bytes"
+	"github.com/femonofsky/articleMaker/article/metrics"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sample returns the value of the series line of the default registry, zero when it is not written yet
+func sample(t *testing.T, series string) float64 {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := metrics.Default.WriteTo(&buf); err != nil {
+		t.Fatalf("could not write metrics: %v", err)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(line, series+" "), 64)
+			if err != nil {
+				t.Fatalf("invalid sample %q: %v", line, err)
+			}
+			return v
+		}
+	}
+	return 0
+}
+
+func TestQueryMetrics(t *testing.T) {
+	defer refreshAllTable()
+
+	tests := []struct {
+		name   string
+		run    func() error
+		series []string
+	}{
+		{"case 01", func() error { return store.CreateCategory(&Category{Name: "Metered"}) },
+			[]string{`db_queries_total{operation="create",status="ok"}`, `db_query_duration_seconds_count{operation="create"}`}},
+		{"case 02", func() error { return store.db.Create(&Category{Name: "Metered"}).Error },
+			[]string{`db_queries_total{operation="create",status="error"}`, `db_query_duration_seconds_count{operation="create"}`}},
+		{"case 03", func() error { _, err := store.GetCategories(); return err },
+			[]string{`db_queries_total{operation="query",status="ok"}`, `db_query_duration_seconds_count{operation="query"}`}},
+		{"case 04", func() error { _, err := store.getCategory("name = 'Unmetered'"); return err },
+			[]string{`db_queries_total{operation="query",status="ok"}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := make([]float64, len(tt.series))
+			for i, series := range tt.series {
+				before[i] = sample(t, series)
+			}
+			tt.run()
+			for i, series := range tt.series {
+				if got := sample(t, series); got != before[i]+1 {
+					t.Errorf("%v = %v, want %v", series, got, before[i]+1)
+				}
+			}
+		})
+	}
+
+	if open := sample(t, "db_open_connections"); open < 1 {
+		t.Errorf("db_open_connections = %v, want at least 1", open)
+	}
+}
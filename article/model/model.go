@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/femonofsky/articleMaker/article/config"
 	"github.com/jinzhu/gorm"
+	"io"
 	// This loads the mysql database driver
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	// This loads the postgres database driver
@@ -12,9 +13,6 @@ import (
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 )
 
-// Global Db
-var Db *gorm.DB
-
 // New return a GORM Database connected to either postgres or MYSQL Database
 func New(config *config.Config) (*gorm.DB, error) {
 	var connect string
@@ -29,6 +27,8 @@ func New(config *config.Config) (*gorm.DB, error) {
 			config.DB.Host, config.DB.Port, config.DB.User, config.DB.Name, config.DB.Password)
 	case "sqlite3":
 		connect = config.DB.Name
+	case MemoryDriver:
+		return nil, fmt.Errorf("DB_DRIVER (%s) has no database, open it with NewStore", config.DB.Driver)
 	default:
 		return nil, fmt.Errorf("DB_DRIVER (%s) not support ", config.DB.Driver)
 	}
@@ -40,16 +40,42 @@ func New(config *config.Config) (*gorm.DB, error) {
 	if config.DB.Driver == "sqlite3" {
 		DB.Exec("PRAGMA foreign_keys = ON")
 	}
-	Db = DB
+	instrument(DB)
 	return DB, nil
 }
 
+// NewStore opens the Store of the driver of config, a MemoryStore for the memory driver and otherwise
+// a GormStore of the database of New. The closer releases the database once the store is no longer used
+func NewStore(config *config.Config) (Store, io.Closer, error) {
+	if config.DB.Driver == MemoryDriver {
+		return NewMemoryStore(), nopCloser{}, nil
+	}
+	DB, err := New(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewGormStore(DB), DB, nil
+}
+
+// nopCloser is the closer of the stores without a database
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// MigrateStore migrates the tables of a GormStore, the other stores need no migration
+func MigrateStore(store Store) {
+	if s, ok := store.(*GormStore); ok {
+		Migrate(s.db)
+	}
+}
+
 // DBMigrate will create and migrate the tables, and then make the some relationships if necessary
 func Migrate(db *gorm.DB) *gorm.DB {
 	// Database Migration the schema
 	db.Debug().AutoMigrate(&Article{}, &Category{}, &Publisher{})
-	db.Model(&Article{}).AddForeignKey("category_name", "categories(name)", "CASCADE", "CASCADE")
-	db.Model(&Article{}).AddForeignKey("publisher_name", "publishers(name)", "CASCADE", "CASCADE")
+	// renames cascade to the articles, deleting a category or publisher they still reference fails
+	db.Model(&Article{}).AddForeignKey("category_name", "categories(name)", "RESTRICT", "CASCADE")
+	db.Model(&Article{}).AddForeignKey("publisher_name", "publishers(name)", "RESTRICT", "CASCADE")
 	return db
 }
 
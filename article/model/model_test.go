@@ -0,0 +1,112 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/femonofsky/articleMaker/article/config"
+	"github.com/jinzhu/gorm"
+	"reflect"
+	"testing"
+)
+
+func TestHealthChecks(t *testing.T) {
+	empty, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	closed, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	defer empty.Close()
+	closed.Close()
+
+	tests := []struct {
+		name        string
+		store       *GormStore
+		pingErr     string
+		migratedErr string
+	}{
+		{"case 01", store, "", ""},
+		{"case 02", NewGormStore(empty), "", "tables not migrated: articles, categories, publishers"},
+		{"case 03", NewGormStore(closed), "sql: database is closed", "tables not migrated: articles, categories, publishers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.store.Ping(); errString(err) != tt.pingErr {
+				t.Errorf("Ping() = %v, want %q", err, tt.pingErr)
+			}
+			if err := tt.store.Migrated(); errString(err) != tt.migratedErr {
+				t.Errorf("Migrated() = %v, want %q", err, tt.migratedErr)
+			}
+		})
+	}
+}
+
+// NewStore opens the store of the driver, migrated by MigrateStore
+func TestNewStore(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		store  string
+		err    string
+	}{
+		{"case 01", MemoryDriver, "*model.MemoryStore", ""},
+		{"case 02", "sqlite3", "*model.GormStore", ""},
+		{"case 03", "oracle", "<nil>", "DB_DRIVER (oracle) not support "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{DB: config.DB{Driver: tt.driver, Name: ":memory:"}}
+			s, closer, err := NewStore(cfg)
+			if got := reflect.TypeOf(s); errString(err) != tt.err || fmt.Sprint(got) != tt.store {
+				t.Fatalf("NewStore() = %v, %v, want %v, %q", got, err, tt.store, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			MigrateStore(s)
+			if err := s.Migrated(); err != nil {
+				t.Errorf("Migrated() = %v after MigrateStore", err)
+			}
+			if err := closer.Close(); err != nil {
+				t.Errorf("Close() = %v", err)
+			}
+		})
+	}
+
+	if _, err := New(&config.Config{DB: config.DB{Driver: MemoryDriver}}); err == nil {
+		t.Errorf("New() of the memory driver = nil error, want an error")
+	}
+}
+
+// a transaction whose context ends before the commit is rolled back and answers ErrCanceled
+func TestGormStoreTransaction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bound := store.WithContext(ctx).(*GormStore)
+	err := bound.transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&Category{Name: "Rolled back"}).Error; err != nil {
+			return err
+		}
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("transaction() = %v, want ErrCanceled", err)
+	}
+	if _, err := store.getCategory(Category{Name: "Rolled back"}); err != ErrCategoryNotFound {
+		t.Errorf("getCategory() = %v, want ErrCategoryNotFound", err)
+	}
+}
+
+// errString is the message of err, empty when it is nil
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to the article and returns the patched copy.
+// The patch works on the json form of the article so it uses the same field names and date format
+func (article *Article) MergePatch(patch []byte) (*Article, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("unable to decode merge patch: %v", err)
+	}
+
+	current, err := article.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var target interface{}
+	if err := json.Unmarshal(current, &target); err != nil {
+		return nil, err
+	}
+
+	merged, err := json.Marshal(mergePatch(target, patchValue))
+	if err != nil {
+		return nil, err
+	}
+	patched := &Article{}
+	if err := json.Unmarshal(merged, patched); err != nil {
+		if verr, ok := err.(*ValidationError); ok {
+			return nil, verr
+		}
+		return nil, fmt.Errorf("merge patch does not give a valid article: %v", err)
+	}
+	patched.ID = article.ID
+	patched.CreatedAt = article.CreatedAt
+	return patched, nil
+}
+
+// mergePatch is the MergePatch algorithm of RFC 7396 on decoded json values
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
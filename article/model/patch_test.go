@@ -0,0 +1,80 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Merge Patch an Article
+func TestArticleMergePatch(t *testing.T) {
+	publishedAt := time.Date(2020, time.February, 25, 19, 2, 35, 0, time.UTC)
+	article := &Article{ID: 3, Title: "Money", Body: "Money is good", CategoryName: "social",
+		PublisherName: "femonofsky", PublishedAt: publishedAt}
+
+	tests := []struct {
+		name    string
+		args    string
+		want    Article
+		wantErr bool
+	}{
+		{"case 01", `{"body": "Money is bad"}`, Article{ID: 3, Title: "Money", Body: "Money is bad",
+			CategoryName: "social", PublisherName: "femonofsky", PublishedAt: publishedAt}, false},
+		{"case 02", `{"published_at": null, "category": "news"}`, Article{ID: 3, Title: "Money", Body: "Money is good",
+			CategoryName: "news", PublisherName: "femonofsky"}, false},
+		{"case 03", `{"id": 9, "unknown": {"a": 1}}`, Article{ID: 3, Title: "Money", Body: "Money is good",
+			CategoryName: "social", PublisherName: "femonofsky", PublishedAt: publishedAt}, false},
+		{"case 04", `{"published_at": "yesterday"}`, Article{}, true},
+		{"case 05", `{"title": `, Article{}, true},
+		{"case 06", `["title"]`, Article{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := article.MergePatch([]byte(tt.args))
+			if err != nil && !tt.wantErr {
+				t.Errorf("unable to patch article:%v", err)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error patching with %v", tt.args)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, &tt.want) {
+				t.Errorf("MergePatch() = %+v, want %+v", got, &tt.want)
+			}
+		})
+	}
+}
+
+// RFC 7396 examples
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target interface{}
+		patch  interface{}
+		want   interface{}
+	}{
+		{"case 01", map[string]interface{}{"a": "b"}, map[string]interface{}{"a": "c"}, map[string]interface{}{"a": "c"}},
+		{"case 02", map[string]interface{}{"a": "b"}, map[string]interface{}{"b": "c"},
+			map[string]interface{}{"a": "b", "b": "c"}},
+		{"case 03", map[string]interface{}{"a": "b", "b": "c"}, map[string]interface{}{"a": nil},
+			map[string]interface{}{"b": "c"}},
+		{"case 04", map[string]interface{}{"a": []interface{}{"b"}}, map[string]interface{}{"a": "c"},
+			map[string]interface{}{"a": "c"}},
+		{"case 05", map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+			map[string]interface{}{"a": map[string]interface{}{"b": "d", "c": nil}},
+			map[string]interface{}{"a": map[string]interface{}{"b": "d"}}},
+		{"case 06", []interface{}{"a", "b"}, []interface{}{"c"}, []interface{}{"c"}},
+		{"case 07", "string", map[string]interface{}{"a": "b"}, map[string]interface{}{"a": "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePatch(tt.target, tt.patch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,181 @@
 package model
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"github.com/jinzhu/gorm"
+	"io"
 )
 
 // Publisher Defines the structure of a Publisher
 type Publisher struct {
 	gorm.Model
-	Name string `sql:"unique;not null" json:"name"`
+	Name         string `sql:"unique;not null" json:"name" validate:"required"`
+	DisplayName  string `json:"display_name"`
+	Bio          string `sql:"type:text" json:"bio"`
+	Website      string `json:"website" validate:"omitempty,url"`
+	ContactEmail string `json:"contact_email" validate:"omitempty,email"`
+}
+
+// UnmarshalJSON parses the json string, only the name and profile can be set by a client
+func (publisher *Publisher) UnmarshalJSON(data []byte) error {
+	var auxPublisher struct {
+		Name         string `json:"name"`
+		DisplayName  string `json:"display_name"`
+		Bio          string `json:"bio"`
+		Website      string `json:"website"`
+		ContactEmail string `json:"contact_email"`
+	}
+
+	dec := json.NewDecoder(bytes.NewBuffer(data))
+	if err := dec.Decode(&auxPublisher); err != nil {
+		return fmt.Errorf("unable to decode %v", err)
+	}
+	publisher.Name = auxPublisher.Name
+	publisher.DisplayName = auxPublisher.DisplayName
+	publisher.Bio = auxPublisher.Bio
+	publisher.Website = auxPublisher.Website
+	publisher.ContactEmail = auxPublisher.ContactEmail
+	return nil
+}
+
+// MarshalJSON writes the publisher in the custom format
+func (publisher *Publisher) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		ID           uint   `json:"id"`
+		Name         string `json:"name"`
+		DisplayName  string `json:"display_name"`
+		Bio          string `json:"bio"`
+		Website      string `json:"website"`
+		ContactEmail string `json:"contact_email"`
+		CreatedAt    string `json:"created_at"`
+		UpdatedAt    string `json:"updated_at"`
+	}{
+		ID:           publisher.ID,
+		Name:         publisher.Name,
+		DisplayName:  publisher.DisplayName,
+		Bio:          publisher.Bio,
+		Website:      publisher.Website,
+		ContactEmail: publisher.ContactEmail,
+		CreatedAt:    publisher.CreatedAt.Format(DateTimeLayout),
+		UpdatedAt:    publisher.UpdatedAt.Format(DateTimeLayout),
+	})
+}
+
+// Validate: check if all Publisher fields met requirements
+func (publisher *Publisher) Validate() error {
+	return validateStruct(publisher)
+}
+
+// Publishers of a collection of Publisher
+type Publishers []*Publisher
+
+// ErrPublisherNotFound publisher not found error
+var ErrPublisherNotFound = newError(ErrNotFound, "publisher not found")
+
+// ErrPublisherExists is returned when another publisher already has the name
+var ErrPublisherExists = newError(ErrConflict, "publisher name already exists")
+
+// ErrPublisherInUse is returned when deleting a publisher that still has articles
+var ErrPublisherInUse = newError(ErrConflict, "publisher still has articles, move or delete them first")
+
+// GetPublishers returns all publishers ordered by name
+func (s *GormStore) GetPublishers() (Publishers, error) {
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	publishers := Publishers{}
+	if err := s.db.Order("name").Find(&publishers).Error; err != nil {
+		return nil, storage(err)
+	}
+	return publishers, nil
+}
+
+// GetPublisher get publisher by ID
+func (s *GormStore) GetPublisher(id int) (*Publisher, error) {
+	return s.getPublisher(id)
+}
+
+// GetPublisherByName get publisher by name
+func (s *GormStore) GetPublisherByName(name string) (*Publisher, error) {
+	return s.getPublisher(Publisher{Name: name})
+}
+
+// getPublisher get publisher by the primary key or the fields of query
+func (s *GormStore) getPublisher(query interface{}) (*Publisher, error) {
+	if err := s.done(); err != nil {
+		return nil, err
+	}
+	publisher := &Publisher{}
+	if err := s.db.First(publisher, query).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrPublisherNotFound
+		}
+		return nil, storage(err)
+	}
+	return publisher, nil
+}
+
+// CreatePublisher create new Publisher
+func (s *GormStore) CreatePublisher(publisher *Publisher) error {
+	if _, err := s.getPublisher(Publisher{Name: publisher.Name}); err != ErrPublisherNotFound {
+		if err == nil {
+			return ErrPublisherExists
+		}
+		return err
+	}
+	return storage(s.transaction(func(tx *gorm.DB) error {
+		return tx.Create(publisher).Error
+	}))
+}
+
+// UpdatePublisher replaces the name and profile of the publisher with the given ID.
+// Articles follow a new name through the publisher_name foreign key (ON UPDATE CASCADE) and move to their next version
+func (s *GormStore) UpdatePublisher(id int, publisher *Publisher) error {
+	current, err := s.GetPublisher(id)
+	if err != nil {
+		return err
+	}
+	if current.Name != publisher.Name {
+		if _, err := s.getPublisher(Publisher{Name: publisher.Name}); err != ErrPublisherNotFound {
+			if err == nil {
+				return ErrPublisherExists
+			}
+			return err
+		}
+	}
+
+	err = s.renameReferenced("publisher_name", current.Name, publisher.Name, func(tx *gorm.DB) error {
+		return tx.Model(current).Updates(map[string]interface{}{
+			"name":          publisher.Name,
+			"display_name":  publisher.DisplayName,
+			"bio":           publisher.Bio,
+			"website":       publisher.Website,
+			"contact_email": publisher.ContactEmail,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	return storage(s.db.First(publisher, current.ID).Error)
+}
+
+// DeletePublisher delete a publisher using its ID, refused while articles still reference it
+func (s *GormStore) DeletePublisher(id int) error {
+	publisher, err := s.GetPublisher(id)
+	if err != nil {
+		return err
+	}
+	return s.deleteReferenced(publisher, "publisher_name", publisher.Name, ErrPublisherInUse)
+}
+
+// SerializePublisher convert request into Publisher object
+func SerializePublisher(r io.ReadCloser) (*Publisher, error) {
+	publisher := &Publisher{}
+	if err := json.NewDecoder(r).Decode(publisher); err != nil {
+		return nil, fmt.Errorf("unable to decode json request body: %v", err)
+	}
+	defer r.Close()
+	return publisher, nil
 }
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"testing"
+)
+
+// Validate Publisher profile
+func TestPublisherValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    Publisher
+		wantErr bool
+	}{
+		{"case 01", Publisher{Name: "tunde"}, false},
+		{"case 02", Publisher{Name: "tunde", Website: "https://tunde.dev", ContactEmail: "desk@tunde.dev"}, false},
+		{"case 03", Publisher{}, true},
+		{"case 04", Publisher{Name: "tunde", Website: "tunde"}, true},
+		{"case 05", Publisher{Name: "tunde", ContactEmail: "tunde"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.Validate()
+			if err != nil && !tt.wantErr {
+				t.Errorf("unable to validate data:%v", err)
+			}
+			if err == nil && tt.wantErr {
+				t.Errorf("expected validation error for %+v", tt.args)
+			}
+		})
+	}
+}
+
+// Update Publisher
+func TestUpdatePublisher(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := Article{Title: "Money", Body: "Money is good", CategoryName: "social", PublisherName: "femonofsky"}
+	if err := store.CreateArticle(&article); err != nil {
+		t.Fatalf("unable to create new article %v", err)
+	}
+	if err := store.CreatePublisher(&Publisher{Name: "tunde"}); err != nil {
+		t.Fatalf("unable to create publisher %v", err)
+	}
+	femonofsky, err := store.GetPublisherByName("femonofsky")
+	if err != nil {
+		t.Fatalf("publisher was not created from article: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		id      uint
+		args    Publisher
+		wantErr bool
+	}{
+		{"case 01", femonofsky.ID, Publisher{Name: "femonofsky", Bio: "Writes about money"}, false},
+		{"case 02", femonofsky.ID, Publisher{Name: "femi", DisplayName: "Femi"}, false},
+		{"case 03", femonofsky.ID, Publisher{Name: "tunde"}, true},
+		{"case 04", 990, Publisher{Name: "sola"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.UpdatePublisher(int(tt.id), &tt.args)
+			if err != nil && !tt.wantErr {
+				t.Errorf("unable to update publisher:%v", err)
+			}
+			if err == nil && tt.wantErr {
+				t.Errorf("expected error updating to %+v", tt.args)
+			}
+		})
+	}
+
+	got, err := store.GetArticle(int(article.ID))
+	if err != nil {
+		t.Fatalf("unable to get article %v", err)
+	}
+	if got.PublisherName != "femi" {
+		t.Errorf("article publisher was not renamed got: %v want: %v", got.PublisherName, "femi")
+	}
+	// only the rename is a new version of the article, not the profile change
+	if got.Version != 2 {
+		t.Errorf("article version got: %v want: %v", got.Version, 2)
+	}
+	publisher, _ := store.GetPublisherByName("femi")
+	if publisher.DisplayName != "Femi" || publisher.Bio != "" {
+		t.Errorf("publisher profile was not replaced got: %+v", publisher)
+	}
+}
+
+// Delete Publisher
+func TestDeletePublisher(t *testing.T) {
+	if err := refreshAllTable(); err != nil {
+		t.Fatal("unable to refreshTable")
+	}
+	article := Article{Title: "Money", Body: "Money is good", CategoryName: "social", PublisherName: "femonofsky"}
+	if err := store.CreateArticle(&article); err != nil {
+		t.Fatalf("unable to create new article %v", err)
+	}
+	empty := Publisher{Name: "tunde"}
+	if err := store.CreatePublisher(&empty); err != nil {
+		t.Fatalf("unable to create publisher %v", err)
+	}
+	femonofsky, _ := store.GetPublisherByName("femonofsky")
+
+	tests := []struct {
+		name string
+		id   uint
+		want error
+	}{
+		{"case 01", femonofsky.ID, ErrPublisherInUse},
+		{"case 02", empty.ID, nil},
+		{"case 03", empty.ID, ErrPublisherNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.DeletePublisher(int(tt.id)); err != tt.want {
+				t.Errorf("DeletePublisher() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
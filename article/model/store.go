@@ -0,0 +1,176 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"strings"
+)
+
+// ArticleStore keeps the articles
+type ArticleStore interface {
+	GetArticlesPage(filter ArticleFilter, page Page) (Articles, int, error)
+	EachArticle(filter ArticleFilter, fn func(*Article) error) error
+	GetArticle(id int) (*Article, error)
+	CreateArticle(article *Article) error
+	UpdateArticle(id int, article *Article, version uint) error
+	DeleteArticle(id int, version uint) error
+	ImportArticles(articles Articles, atomic bool) []error
+}
+
+// CategoryStore keeps the categories
+type CategoryStore interface {
+	GetCategories() (Categories, error)
+	GetCategory(id int) (*Category, error)
+	CreateCategory(category *Category) error
+	UpdateCategory(id int, category *Category) error
+	DeleteCategory(id int) error
+}
+
+// PublisherStore keeps the publishers
+type PublisherStore interface {
+	GetPublishers() (Publishers, error)
+	GetPublisher(id int) (*Publisher, error)
+	GetPublisherByName(name string) (*Publisher, error)
+	CreatePublisher(publisher *Publisher) error
+	UpdatePublisher(id int, publisher *Publisher) error
+	DeletePublisher(id int) error
+}
+
+// Store keeps the articles with their categories and publishers
+type Store interface {
+	ArticleStore
+	CategoryStore
+	PublisherStore
+	// Ping checks that the storage answers
+	Ping() error
+	// Migrated checks that the storage is ready for the models
+	Migrated() error
+	// WithContext returns the store bound to ctx, it answers ErrCanceled once ctx ended
+	// and its changes are not stored unless they complete before
+	WithContext(ctx context.Context) Store
+}
+
+// GormStore is a Store of a SQL database opened by New
+type GormStore struct {
+	db *gorm.DB
+	// ctx is the context set by WithContext, nil when the store is not bound
+	ctx context.Context
+}
+
+// NewGormStore creates a Store of db
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// WithContext returns the store bound to ctx, its changes run in transactions that are rolled back when ctx ends
+func (s *GormStore) WithContext(ctx context.Context) Store {
+	return &GormStore{db: s.db, ctx: ctx}
+}
+
+// done returns ErrCanceled once the context of the store ended
+func (s *GormStore) done() error {
+	if s.ctx == nil || s.ctx.Err() == nil {
+		return nil
+	}
+	return canceled(s.ctx.Err())
+}
+
+// transaction runs fn in a transaction of the context of the store. It is rolled back when fn fails
+// or the context ends before it is committed, the database errors of an ended context become ErrCanceled
+func (s *GormStore) transaction(fn func(tx *gorm.DB) error) (err error) {
+	if err := s.done(); err != nil {
+		return err
+	}
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tx := s.db.BeginTx(ctx, &sql.TxOptions{})
+	if err := tx.Error; err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+		if _, ok := storage(err).(*StorageError); ok && s.done() != nil {
+			err = s.done()
+		}
+	}()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// Ping checks that the database answers
+func (s *GormStore) Ping() error {
+	return s.db.DB().Ping()
+}
+
+// Migrated checks that Migrate created the table of every model
+func (s *GormStore) Migrated() error {
+	var missing []string
+	for _, table := range []interface{}{&Article{}, &Category{}, &Publisher{}} {
+		if !s.db.HasTable(table) {
+			missing = append(missing, s.db.NewScope(table).TableName())
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("tables not migrated: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+var _ Store = (*GormStore)(nil)
+
+// deleteReferenced hard deletes record, refused with inUse while the column of an article holds its name.
+// The row is locked first, an article created meanwhile waits for the delete and then fails its foreign key
+func (s *GormStore) deleteReferenced(record interface{}, column, name string, inUse error) error {
+	return storage(s.transaction(func(tx *gorm.DB) error {
+		// sqlite has no row locks, it runs one write transaction at a time
+		if tx.Dialect().GetName() != "sqlite3" {
+			if err := tx.Set("gorm:query_option", "FOR UPDATE").First(record).Error; err != nil {
+				return err
+			}
+		}
+		count := 0
+		if err := tx.Model(&Article{}).Where(column+" = ?", name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return inUse
+		}
+		// Hard delete so the unique name can be used again
+		return tx.Unscoped().Delete(record).Error
+	}))
+}
+
+// renameReferenced runs update in a transaction, it renames a record whose name the column of the articles holds.
+// When the name changes the articles follow it and move to their next version
+func (s *GormStore) renameReferenced(column, oldName, newName string, update func(tx *gorm.DB) error) error {
+	return storage(s.transaction(func(tx *gorm.DB) error {
+		if oldName == newName {
+			return update(tx)
+		}
+		// the articles change with the name, a new version invalidates their ETags
+		err := tx.Model(&Article{}).Where(column+" = ?", oldName).
+			UpdateColumn("version", gorm.Expr("version + 1")).Error
+		if err != nil {
+			return err
+		}
+		if err := update(tx); err != nil {
+			return err
+		}
+		// sqlite does not get the foreign key from Migrate, so cascade the rename by hand.
+		// Where the constraint exists this matches no rows.
+		return tx.Model(&Article{}).Where(column+" = ?", oldName).UpdateColumn(column, newName).Error
+	}))
+}